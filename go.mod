@@ -2,7 +2,10 @@ module github.com/example/go-mod-clone
 
 go 1.21
 
-require github.com/spf13/cobra v1.7.0
+require (
+	github.com/spf13/cobra v1.7.0
+	golang.org/x/crypto v0.31.0
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -0,0 +1,147 @@
+// Package accesslog writes one JSON line per served request to a file that
+// is rotated by size, as an audit trail of who downloaded which module.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Defaults of the rotation limits.
+const (
+	DefaultMaxSize    = 100 << 20 // bytes
+	DefaultMaxBackups = 10
+)
+
+// Entry is one served request.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	ClientIP   string    `json:"client_ip"`
+	User       string    `json:"user,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Module     string    `json:"module,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	Artifact   string    `json:"artifact"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+// Logger appends entries to a file. When the file would grow beyond the
+// maximum size it is renamed to file.1, older backups shift to file.2 and
+// so on, and the oldest beyond the backup limit is removed. A Logger is
+// safe for concurrent use.
+type Logger struct {
+	file       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens file for appending, creating it and its directory if needed.
+// Zero limits take the defaults.
+func Open(file string, maxSize int64, maxBackups int) (*Logger, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+	l := &Logger{file: file, maxSize: maxSize, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// Log writes e as one JSON line.
+func (l *Logger) Log(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return fmt.Errorf("access log %s is closed", l.file)
+	}
+	var rotateErr error
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if rotateErr = l.rotate(); l.f == nil {
+			return rotateErr
+		}
+	}
+	n, err := l.f.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rotateErr
+}
+
+// rotate moves the current file to the first backup and reopens it. If the
+// files cannot be shifted, logging continues in the current file.
+func (l *Logger) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+	err := l.shift()
+	if oerr := l.open(); oerr != nil {
+		return oerr
+	}
+	return err
+}
+
+func (l *Logger) shift() error {
+	os.Remove(backup(l.file, l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backup(l.file, i), backup(l.file, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.file, backup(l.file, 1))
+}
+
+func backup(file string, i int) string {
+	return fmt.Sprintf("%s.%d", file, i)
+}
+
+// Close closes the file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
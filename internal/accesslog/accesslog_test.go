@@ -0,0 +1,78 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readEntries(t *testing.T, file string) []Entry {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("bad line %q: %v", sc.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLogger_Rotates(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "logs", "access.log")
+	line, _ := json.Marshal(Entry{Path: "/example.com/m/@v/list"})
+	// Two entries fit in a file
+	l, err := Open(file, int64(2*(len(line)+1)), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		if err := l.Log(Entry{Path: "/example.com/m/@v/list", Status: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries 0-1 and 2-3 were rotated out of the two backups
+	for file, want := range map[string][]int{
+		file:        {6},
+		file + ".1": {4, 5},
+		file + ".2": {2, 3},
+	} {
+		entries := readEntries(t, file)
+		if len(entries) != len(want) {
+			t.Errorf("%s has %d entries, want %d", filepath.Base(file), len(entries), len(want))
+			continue
+		}
+		for i, e := range entries {
+			if e.Status != want[i] {
+				t.Errorf("%s entry %d = %d, want %d", filepath.Base(file), i, e.Status, want[i])
+			}
+		}
+	}
+	if _, err := os.Stat(file + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond the limit exists: %v", err)
+	}
+
+	// Reopening appends to the current file
+	l, err = Open(file, 1<<20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Log(Entry{Status: 7})
+	l.Close()
+	if n := len(readEntries(t, file)); n != 2 {
+		t.Errorf("%d entries after reopening, want 2", n)
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Everyone in an ACL rule's users grants access to every authenticated user.
+const Everyone = "*"
+
+// ACL maps users and groups to the module path prefixes they may read.
+type ACL struct {
+	Groups map[string][]string `json:"groups"` // group -> members
+	Rules  []ACLRule           `json:"rules"`
+}
+
+// ACLRule grants read access below a path prefix. The longest prefix that
+// matches a path decides; a path no rule matches is denied.
+type ACLRule struct {
+	Prefix string   `json:"prefix"` // module path prefix, matched on element boundaries; "" matches all
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// LoadACL reads an ACL from a JSON file.
+func LoadACL(file string) (*ACL, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var acl ACL
+	if err := json.Unmarshal(data, &acl); err != nil {
+		return nil, fmt.Errorf("invalid ACL %s: %w", file, err)
+	}
+	for i, r := range acl.Rules {
+		if len(r.Users) == 0 && len(r.Groups) == 0 {
+			return nil, fmt.Errorf("ACL rule %d (%q) grants nobody", i+1, r.Prefix)
+		}
+		for _, g := range r.Groups {
+			if _, ok := acl.Groups[g]; !ok {
+				return nil, fmt.Errorf("ACL rule %d (%q) names unknown group %q", i+1, r.Prefix, g)
+			}
+		}
+		acl.Rules[i].Prefix = strings.Trim(r.Prefix, "/")
+	}
+	return &acl, nil
+}
+
+// Allowed reports whether user may read path.
+func (a *ACL) Allowed(user, path string) bool {
+	var best *ACLRule
+	for i := range a.Rules {
+		r := &a.Rules[i]
+		if matchPrefix(r.Prefix, path) && (best == nil || len(r.Prefix) > len(best.Prefix)) {
+			best = r
+		}
+	}
+	if best == nil {
+		return false
+	}
+	for _, u := range best.Users {
+		if u == user || u == Everyone {
+			return true
+		}
+	}
+	for _, g := range best.Groups {
+		for _, member := range a.Groups[g] {
+			if member == user {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPrefix reports whether prefix is path or one of its parent paths.
+func matchPrefix(prefix, path string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
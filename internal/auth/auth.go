@@ -0,0 +1,156 @@
+// Package auth authenticates proxy clients and decides which module paths
+// they may read.
+//
+// Credentials are accepted the way the go command sends them: HTTP basic
+// auth from .netrc (GOAUTH=netrc, the default) or an Authorization header
+// from a GOAUTH command. A static token may be sent as a bearer token or as
+// the basic auth password with any user name.
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authenticator identifies the user of a request. It returns "" when the
+// request carries no credentials it recognizes.
+type Authenticator interface {
+	Authenticate(r *http.Request) string
+}
+
+// Chain tries each authenticator in turn.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) string {
+	for _, a := range c {
+		if user := a.Authenticate(r); user != "" {
+			return user
+		}
+	}
+	return ""
+}
+
+// Tokens authenticates static tokens.
+type Tokens struct {
+	users map[string]string // token -> user
+}
+
+// LoadTokens reads a token file with one "<user> <token>" pair per line.
+// Blank lines and lines starting with # are ignored.
+func LoadTokens(file string) (*Tokens, error) {
+	t := &Tokens{users: make(map[string]string)}
+	err := readLines(file, func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return errors.New(`want "<user> <token>"`)
+		}
+		if _, dup := t.users[fields[1]]; dup {
+			return fmt.Errorf("duplicate token for %s", fields[0])
+		}
+		t.users[fields[1]] = fields[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Tokens) Authenticate(r *http.Request) string {
+	token := ""
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	} else if _, password, ok := r.BasicAuth(); ok {
+		token = password
+	}
+	if token == "" {
+		return ""
+	}
+	// Compare against every token so the time taken does not reveal a match
+	user := ""
+	for known, u := range t.users {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			user = u
+		}
+	}
+	return user
+}
+
+// Htpasswd authenticates basic auth against an htpasswd file.
+type Htpasswd struct {
+	hashes map[string]string // user -> hash
+}
+
+// LoadHtpasswd reads an htpasswd file with bcrypt ("htpasswd -B") or SHA-1
+// ("htpasswd -s") hashes. Other hash types are rejected rather than
+// silently never matching.
+func LoadHtpasswd(file string) (*Htpasswd, error) {
+	h := &Htpasswd{hashes: make(map[string]string)}
+	err := readLines(file, func(line string) error {
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return errors.New(`want "<user>:<hash>"`)
+		}
+		if !strings.HasPrefix(hash, "$2") && !strings.HasPrefix(hash, "{SHA}") {
+			return fmt.Errorf("unsupported hash for %s; use bcrypt (htpasswd -B)", user)
+		}
+		h.hashes[user] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Htpasswd) Authenticate(r *http.Request) string {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return ""
+	}
+	hash, ok := h.hashes[user]
+	if !ok {
+		return ""
+	}
+	if strings.HasPrefix(hash, "{SHA}") {
+		sum := sha1.Sum([]byte(password))
+		if subtle.ConstantTimeCompare([]byte(hash[len("{SHA}"):]), []byte(base64.StdEncoding.EncodeToString(sum[:]))) == 1 {
+			return user
+		}
+		return ""
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+		return user
+	}
+	return ""
+}
+
+// readLines calls fn for every non-blank, non-comment line of file.
+func readLines(file string, fn func(line string) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", file, n, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestChain_TokensAndHtpasswd(t *testing.T) {
+	tokens, err := LoadTokens(writeFile(t, "tokens", "# CI\nci s3cr3t-token\n"))
+	if err != nil {
+		t.Fatalf("LoadTokens failed: %v", err)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("alice-pw"), bcrypt.MinCost)
+	// {SHA} hash of "bob-pw"
+	htpasswd, err := LoadHtpasswd(writeFile(t, "htpasswd", "alice:"+string(hash)+"\nbob:{SHA}bOWgjgJew8XNjPXTyFghAc+ha1M=\n"))
+	if err != nil {
+		t.Fatalf("LoadHtpasswd failed: %v", err)
+	}
+	chain := Chain{tokens, htpasswd}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		bearer   string
+		want     string
+	}{
+		{"bearer token", "", "", "s3cr3t-token", "ci"},
+		{"token as netrc password", "anything", "s3cr3t-token", "", "ci"},
+		{"bcrypt", "alice", "alice-pw", "", "alice"},
+		{"sha1", "bob", "bob-pw", "", "bob"},
+		{"wrong password", "alice", "bob-pw", "", ""},
+		{"unknown token", "", "", "guess", ""},
+		{"no credentials", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/example.com/a/@v/list", nil)
+		if tt.user != "" {
+			r.SetBasicAuth(tt.user, tt.password)
+		}
+		if tt.bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+		if got := chain.Authenticate(r); got != tt.want {
+			t.Errorf("%s: Authenticate = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadHtpasswd_RejectsUnsupportedHashes(t *testing.T) {
+	if _, err := LoadHtpasswd(writeFile(t, "htpasswd", "carol:$apr1$abc$def\n")); err == nil {
+		t.Error("MD5 htpasswd entry should be rejected")
+	}
+}
+
+func TestACL_Allowed(t *testing.T) {
+	acl, err := LoadACL(writeFile(t, "acl.json", `{
+  "groups": {"platform": ["alice", "bob"]},
+  "rules": [
+    {"prefix": "", "users": ["*"]},
+    {"prefix": "corp.example.com", "users": ["ci"], "groups": ["platform"]},
+    {"prefix": "corp.example.com/secret/", "users": ["alice"]}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("LoadACL failed: %v", err)
+	}
+
+	tests := []struct {
+		user, path string
+		want       bool
+	}{
+		{"carol", "github.com/spf13/cobra", true},
+		{"carol", "corp.example.com/platform/auth", false},
+		{"bob", "corp.example.com/platform/auth", true},
+		{"ci", "corp.example.com", true},
+		{"bob", "corp.example.com/secret/keys", false},
+		{"alice", "corp.example.com/secret", true},
+		{"carol", "corp.example.company/x", true}, // prefixes match whole elements
+	}
+	for _, tt := range tests {
+		if got := acl.Allowed(tt.user, tt.path); got != tt.want {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", tt.user, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := LoadACL(writeFile(t, "bad.json", `{"rules": [{"prefix": "x", "groups": ["nope"]}]}`)); err == nil {
+		t.Error("Rule naming an unknown group should be rejected")
+	}
+}
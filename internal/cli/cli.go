@@ -1,28 +1,86 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/example/go-mod-clone/internal/accesslog"
+	"github.com/example/go-mod-clone/internal/auth"
+	"github.com/example/go-mod-clone/internal/crosscheck"
 	"github.com/example/go-mod-clone/internal/gomod"
 	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/lookalike"
+	"github.com/example/go-mod-clone/internal/metrics"
+	"github.com/example/go-mod-clone/internal/modzip"
 	"github.com/example/go-mod-clone/internal/packer"
+	"github.com/example/go-mod-clone/internal/policy"
 	"github.com/example/go-mod-clone/internal/resolver"
+	"github.com/example/go-mod-clone/internal/retry"
 	"github.com/example/go-mod-clone/internal/server"
+	"github.com/example/go-mod-clone/internal/storage"
+	"github.com/example/go-mod-clone/internal/upstream"
+	"github.com/example/go-mod-clone/internal/vulndb"
 	"github.com/example/go-mod-clone/internal/worker"
 	"github.com/spf13/cobra"
 )
 
 var (
-	modulesFile    string
-	storageRoot    string
-	workDir        string
-	concurrency    int
-	logLevel       string
-	host           string
-	port           int
-	useCache       bool
-	clearCache     bool
+	modulesFile string
+	storageRoot string
+	workDir     string
+	concurrency int
+	logLevel    string
+	host        string
+	port        int
+	useCache    bool
+	clearCache  bool
+	goProxy     string
+	goNoSumDB   string
+	goPrivate   string
+	goFlags     string
+	cacheTTL    time.Duration
+	strict      bool
+	failReport  string
+	attempts    int
+	retryDelay  time.Duration
+	retryMax    time.Duration
+	policyFile  string
+	policyOut   string
+	sbomDir     string
+	vulnDBURL   string
+	quarantined bool
+	minAge      time.Duration
+	zipReport   string
+	lookalikes  bool
+	lookReport  string
+	crossCheck  string
+	crossReport string
+	metricsFile string
+	tlsCert     string
+	tlsKey      string
+	clientCA    string
+	redirectTo  string
+	tokensFile  string
+	htpasswd    string
+	aclFile     string
+	uploadACL   string
+
+	shutdownTimeout   time.Duration
+	drainDelay        time.Duration
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	uploadTimeout     time.Duration
+	maxHeaderBytes    int
+
+	accessLog        string
+	accessLogMaxSize int64
+	accessLogBackups int
+	trustProxy       bool
 )
 
 var rootCmd = &cobra.Command{
@@ -54,6 +112,28 @@ func init() {
 	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	rootCmd.Flags().BoolVar(&useCache, "use-cache", true, "Use resolution cache to speed up subsequent runs")
 	rootCmd.Flags().BoolVar(&clearCache, "clear-cache", false, "Clear resolution cache before starting")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", resolver.DefaultQueryTTL, "How long cached resolutions of queries such as @latest stay valid")
+	rootCmd.Flags().BoolVar(&strict, "strict", false, "Exit with an error if any module fails to resolve")
+	rootCmd.Flags().StringVar(&failReport, "failure-report", "", "Write resolution failures as JSON to this file")
+	rootCmd.Flags().IntVar(&attempts, "attempts", retry.DefaultPolicy.Attempts, "Attempts per upstream fetch before giving up on transient errors")
+	rootCmd.Flags().DurationVar(&retryDelay, "retry-delay", retry.DefaultPolicy.BaseDelay, "Initial delay between retries, doubled on each attempt")
+	rootCmd.Flags().DurationVar(&retryMax, "retry-max-delay", retry.DefaultPolicy.MaxDelay, "Maximum delay between retries")
+	rootCmd.Flags().DurationVar(&minAge, "min-age", 0, "Do not select versions released more recently than this, e.g. 72h; queries fall back to older versions")
+	rootCmd.Flags().BoolVar(&lookalikes, "lookalikes", false, "Hold modules whose paths resemble popular or already mirrored paths in quarantine for review")
+	rootCmd.Flags().StringVar(&lookReport, "lookalike-report", "", "Write lookalike module paths and the roots that pulled them in as JSON to this file")
+	rootCmd.Flags().StringVar(&crossCheck, "crosscheck", "", "Comma-separated upstreams whose go.mod and zip hashes must agree before packing: proxy URLs or sumdb:<url>")
+	rootCmd.Flags().StringVar(&crossReport, "crosscheck-report", "", "Write upstream hash mismatches as JSON to this file")
+	rootCmd.Flags().StringVar(&policyFile, "policy", "", "Policy file with allow/deny rules and requirements for mirrored modules")
+	rootCmd.Flags().StringVar(&policyOut, "policy-report", "", "Write policy violations as JSON to this file")
+	rootCmd.Flags().StringVar(&zipReport, "zip-report", "", "Write the validation reports of the packed module zips as JSON to this file")
+	rootCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Write run metrics in Prometheus text format to this file, e.g. for the node exporter's textfile collector")
+	rootCmd.Flags().StringVar(&sbomDir, "sbom-dir", "", "Write CycloneDX and SPDX SBOMs of the packed modules to this directory")
+	rootCmd.Flags().BoolVar(&quarantined, "quarantine", false, "Stage new module versions for approval instead of serving them immediately")
+	rootCmd.Flags().StringVar(&vulnDBURL, "vulndb", "", "Mirror the vulnerability database at this URL (e.g. "+vulndb.DefaultURL+") into the storage root")
+	rootCmd.Flags().StringVar(&goProxy, "goproxy", resolver.DefaultGoProxy, "GOPROXY used by the resolver's go commands")
+	rootCmd.Flags().StringVar(&goNoSumDB, "gonosumdb", "", "GONOSUMDB used by the resolver's go commands")
+	rootCmd.Flags().StringVar(&goPrivate, "goprivate", "", "GOPRIVATE used by the resolver's go commands")
+	rootCmd.Flags().StringVar(&goFlags, "goflags", "", "GOFLAGS used by the resolver's go commands")
 
 	rootCmd.MarkFlagRequired("modules")
 
@@ -62,6 +142,28 @@ func init() {
 	serverCmd.Flags().StringVarP(&host, "host", "H", "localhost", "Server host address")
 	serverCmd.Flags().IntVarP(&port, "port", "p", 3000, "Server port")
 	serverCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	serverCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Serve HTTPS with this PEM certificate chain (reloaded on SIGHUP or change)")
+	serverCmd.Flags().StringVar(&tlsKey, "tls-key", "", "PEM private key of --tls-cert")
+	serverCmd.Flags().StringVar(&clientCA, "client-ca", "", "Require client certificates signed by this PEM CA bundle (mutual TLS), except for the health checks")
+	serverCmd.Flags().StringVar(&tokensFile, "auth-tokens", "", `Require authentication; file of "<user> <token>" lines, tokens sent as bearer or basic auth password`)
+	serverCmd.Flags().StringVar(&htpasswd, "htpasswd", "", "Require authentication; htpasswd file with bcrypt or SHA-1 hashes for basic auth (.netrc)")
+	serverCmd.Flags().StringVar(&aclFile, "acl", "", "JSON file mapping users and groups to the module path prefixes they may read")
+	serverCmd.Flags().StringVar(&uploadACL, "upload-acl", "", "Accept uploads through PUT /api/v1/modules/...; JSON file mapping users and groups to the module path prefixes they may publish to")
+	serverCmd.Flags().StringVar(&policyFile, "policy", "", "Policy file that uploaded modules must satisfy")
+	serverCmd.Flags().BoolVar(&quarantined, "quarantine", false, "Stage uploaded module versions for approval instead of serving them immediately")
+	serverCmd.Flags().StringVar(&accessLog, "access-log", "", "Append a JSON line per module request (client, user, module, version, status, bytes, duration) to this file")
+	serverCmd.Flags().Int64Var(&accessLogMaxSize, "access-log-max-size", accesslog.DefaultMaxSize, "Rotate the access log when it would grow beyond this many bytes")
+	serverCmd.Flags().IntVar(&accessLogBackups, "access-log-backups", accesslog.DefaultMaxBackups, "Rotated access logs to keep")
+	serverCmd.Flags().BoolVar(&trustProxy, "trust-proxy", false, "Log the client IP from the last X-Forwarded-For entry; only behind a reverse proxy that appends it")
+	serverCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", server.DefaultShutdownTimeout, "How long in-flight requests may finish after SIGTERM")
+	serverCmd.Flags().DurationVar(&drainDelay, "drain-delay", 0, "How long /readyz fails after SIGTERM before new connections are refused, for load balancers to notice")
+	serverCmd.Flags().DurationVar(&readHeaderTimeout, "read-header-timeout", server.DefaultReadHeaderTimeout, "Maximum time to read request headers")
+	serverCmd.Flags().DurationVar(&readTimeout, "read-timeout", server.DefaultReadTimeout, "Maximum time to read a whole request")
+	serverCmd.Flags().DurationVar(&writeTimeout, "write-timeout", server.DefaultWriteTimeout, "Maximum time to write a response, negative for no limit")
+	serverCmd.Flags().DurationVar(&uploadTimeout, "upload-timeout", server.DefaultUploadTimeout, "Maximum time to read an upload, negative for no limit; replaces --read-timeout for uploads")
+	serverCmd.Flags().DurationVar(&idleTimeout, "idle-timeout", server.DefaultIdleTimeout, "How long idle keep-alive connections stay open")
+	serverCmd.Flags().IntVar(&maxHeaderBytes, "max-header-bytes", server.DefaultMaxHeaderBytes, "Maximum size of request headers")
+	serverCmd.Flags().StringVar(&redirectTo, "redirect-http", "", "Also listen for plain HTTP on this address, e.g. :80, and redirect to HTTPS")
 
 	serverCmd.MarkFlagRequired("storage-root")
 
@@ -75,10 +177,23 @@ func Execute() {
 	}
 }
 
-func runPrefill() error {
+func runPrefill() (err error) {
 	// Setup logger
 	log.SetLevelFromString(logLevel)
 
+	// Run metrics are written however the run ends
+	var stats prefillStats
+	if metricsFile != "" {
+		start := time.Now()
+		defer func() {
+			stats.duration = time.Since(start)
+			stats.success = err == nil
+			if werr := writePrefillMetrics(metricsFile, stats); werr != nil {
+				log.Error("Failed to write metrics: %v", werr)
+			}
+		}()
+	}
+
 	// Validate and setup storage root
 	if storageRoot == "" {
 		storageRoot = os.Getenv("ATHENS_DISK_STORAGE_ROOT")
@@ -106,6 +221,7 @@ func runPrefill() error {
 	log.Debug("Work directory: %s", workDir)
 	log.Debug("Concurrency: %d", concurrency)
 	log.Debug("Use cache: %v", useCache)
+	log.Debug("GOPROXY: %s", goProxy)
 
 	// Clear cache if requested
 	if clearCache {
@@ -126,21 +242,111 @@ func runPrefill() error {
 
 	// Resolve dependencies with cache support
 	log.Info("Resolving dependencies...")
-	res := resolver.NewResolverWithCacheControl(workDir, useCache)
+	var pol *policy.Policy
+	if policyFile != "" {
+		if pol, err = policy.Load(policyFile); err != nil {
+			return fmt.Errorf("failed to load policy: %w", err)
+		}
+		log.Info("Loaded policy from %s (%d rules)", policyFile, len(pol.Rules))
+	}
+
+	var verifier *crosscheck.Verifier
+	if crossCheck != "" {
+		sources, err := crosscheck.ParseSources(crossCheck)
+		if err != nil {
+			return fmt.Errorf("invalid --crosscheck: %w", err)
+		}
+		verifier = crosscheck.NewVerifier(upstream.NewClient(retryPolicy()), sources)
+	}
+
+	var checker *lookalike.Checker
+	if lookalikes || lookReport != "" {
+		// Paths already in the mirror are known in addition to popular ones
+		var known []string
+		if mirrored, err := storage.ListModules(storageRoot); err == nil {
+			for _, m := range mirrored {
+				known = append(known, m.Path)
+			}
+		}
+		checker = lookalike.NewChecker(known)
+	}
+
+	res := resolver.NewResolverWithOptions(workDir, resolver.Options{
+		UseCache:  useCache,
+		GoProxy:   goProxy,
+		GoNoSumDB: goNoSumDB,
+		GoPrivate: goPrivate,
+		GoFlags:   goFlags,
+		QueryTTL:  cacheTTL,
+		Strict:    strict,
+		Retry:     retryPolicy(),
+		Policy:    pol,
+		MinAge:    minAge,
+		Lookalike: checker,
+	})
+	// Packing copies files out of the resolver's module cache, so it is only
+	// removed once this function returns.
+	defer func() {
+		if err := res.Cleanup(); err != nil {
+			log.Warn("Failed to clean up module cache: %v", err)
+		}
+	}()
 	resolvedModules, err := res.ResolveDependencies(modules)
+	resolveFailures := res.Failures()
+	if failReport != "" {
+		if werr := resolver.WriteFailureReport(failReport, resolveFailures); werr != nil {
+			log.Error("Failed to write failure report: %v", werr)
+		} else {
+			log.Info("Wrote failure report to %s", failReport)
+		}
+	}
+	// Violations are collected during resolution and packing; the report
+	// covers both, or only the former if the run stops before packing
+	var violations []policy.Violation
+	if policyOut != "" {
+		defer func() {
+			if werr := policy.WriteReport(policyOut, violations); werr != nil {
+				log.Error("Failed to write policy report: %v", werr)
+			}
+		}()
+	}
+	violations = res.Violations()
 	if err != nil {
 		return fmt.Errorf("failed to resolve dependencies: %w", err)
 	}
 	log.Info("Resolved %d total modules", len(resolvedModules))
+	stats.resolved = len(resolvedModules)
+	stats.unresolved = len(resolveFailures)
+	found := res.Lookalikes()
+	if lookReport != "" {
+		if err := lookalike.WriteReport(lookReport, found); err != nil {
+			log.Error("Failed to write lookalike report: %v", err)
+		}
+	}
+	held := make(map[string]string)
+	if lookalikes {
+		for _, f := range found {
+			held[f.Path] = "lookalike: " + f.String()
+		}
+	}
+	if len(resolveFailures) > 0 {
+		log.Warn("%d modules failed to resolve; the mirror is incomplete (use --strict to fail the run)", len(resolveFailures))
+	}
 
 	// Pack modules
 	log.Info("Packing modules into Athens format...")
-	p := packer.NewPacker(storageRoot)
+	p := packer.NewPackerWithOptions(storageRoot, packer.Options{
+		Policy:     pol,
+		Quarantine: quarantined,
+		CrossCheck: verifier,
+	})
 	pool := worker.NewPool(concurrency)
 
+	var mu sync.Mutex
 	successCount := 0
 	failureCount := 0
 	var failures []string
+	var packed []gomod.Module
 
 	modIdx := 1
 	for modKey, mod := range resolvedModules {
@@ -148,17 +354,65 @@ func runPrefill() error {
 		log.Info("Pack %v/%v %v", modIdx, len(resolvedModules), modKey)
 		modIdx = modIdx + 1
 		pool.Submit(func() {
-			if err := p.Pack(mod); err != nil {
+			var err error
+			if reason, ok := held[mod.Path]; ok {
+				err = p.Stage(mod, reason)
+			} else {
+				err = p.Pack(mod)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
 				failureCount++
 				log.Error("Failed to pack %s@%s: %v", mod.Path, mod.Version, err)
 				failures = append(failures, fmt.Sprintf("%s@%s: %v", mod.Path, mod.Version, err))
 			} else {
 				successCount++
+				packed = append(packed, mod)
 			}
 		})
 	}
 
 	pool.Wait()
+	violations = append(violations, p.Violations()...)
+
+	mismatches := p.Mismatches()
+	if crossReport != "" {
+		if err := crosscheck.WriteReport(crossReport, mismatches); err != nil {
+			log.Error("Failed to write cross-check report: %v", err)
+		}
+	}
+
+	if zipReport != "" {
+		if err := writeZipReport(zipReport, p.ZipReports()); err != nil {
+			log.Error("Failed to write zip validation report: %v", err)
+		} else {
+			log.Info("Wrote zip validation report to %s", zipReport)
+		}
+	}
+
+	if sbomDir != "" {
+		if err := writeSBOMs(sbomDir, modulesFile, packed, p, res.Graph()); err != nil {
+			log.Error("Failed to write SBOM: %v", err)
+			failureCount++
+			failures = append(failures, fmt.Sprintf("SBOM: %v", err))
+		}
+	}
+
+	if vulnDBURL != "" {
+		log.Info("Mirroring vulnerability database from %s...", vulnDBURL)
+		vstats, err := vulndb.Mirror(upstream.NewClient(retryPolicy()), vulnDBURL, vulndb.Dir(storageRoot))
+		if err != nil {
+			log.Error("Failed to mirror vulnerability database: %v", err)
+			failureCount++
+			failures = append(failures, fmt.Sprintf("vulnerability database: %v", err))
+		} else {
+			log.Info("Vulnerability database: %d entries, %d downloaded", vstats.Entries, vstats.Downloaded)
+		}
+	}
+
+	stats.packed = successCount
+	stats.failed = failureCount
 
 	// Print summary
 	log.Info("=====================================")
@@ -166,6 +420,30 @@ func runPrefill() error {
 	log.Info("  Total modules: %d", len(resolvedModules))
 	log.Info("  Packed: %d", successCount)
 	log.Info("  Failed: %d", failureCount)
+	log.Info("  Unresolved: %d", len(resolveFailures))
+	for _, f := range resolveFailures {
+		log.Warn("  - %s@%s (%s)", f.Path, f.Version, f.Kind)
+	}
+	skipped := res.Skipped()
+	log.Info("  Skipped (within --min-age): %d", len(skipped))
+	for _, s := range skipped {
+		if s.Fallback != "" {
+			log.Warn("  - %s@%s (released %s, using %s)", s.Path, s.Version, s.Time.Format(time.RFC3339), s.Fallback)
+		} else {
+			log.Warn("  - %s@%s (released %s)", s.Path, s.Version, s.Time.Format(time.RFC3339))
+		}
+	}
+	log.Info("  Policy violations: %d", len(violations))
+	if verifier != nil {
+		log.Info("  Upstream hash mismatches: %d", len(mismatches))
+	}
+	log.Info("  Lookalike paths: %d", len(found))
+	for _, f := range found {
+		log.Warn("  - %s, via %s", f, f.Root)
+	}
+	if quarantined || len(held) > 0 {
+		log.Info("  Awaiting approval: %d (see 'go-mod-clone quarantine')", p.Staged())
+	}
 	if failureCount > 0 {
 		log.Error("Failed modules:")
 		for _, f := range failures {
@@ -182,6 +460,60 @@ func runPrefill() error {
 	return nil
 }
 
+// prefillStats are the counts of a prefill run exported as metrics.
+type prefillStats struct {
+	resolved   int
+	unresolved int
+	packed     int
+	failed     int
+	duration   time.Duration
+	success    bool
+}
+
+// writePrefillMetrics writes the metrics of a run to file.
+func writePrefillMetrics(file string, stats prefillStats) error {
+	r := metrics.NewRegistry()
+	set := func(name, help string, v float64) {
+		r.Gauge(name, help).Set(v)
+	}
+	success := 0.0
+	if stats.success {
+		success = 1
+	}
+	set("gomodclone_prefill_modules_resolved", "Module versions resolved in the last prefill run.", float64(stats.resolved))
+	set("gomodclone_prefill_modules_unresolved", "Module versions that failed to resolve in the last prefill run.", float64(stats.unresolved))
+	set("gomodclone_prefill_modules_packed", "Module versions packed in the last prefill run.", float64(stats.packed))
+	set("gomodclone_prefill_modules_failed", "Module versions that failed to pack in the last prefill run.", float64(stats.failed))
+	set("gomodclone_prefill_duration_seconds", "Duration of the last prefill run.", stats.duration.Seconds())
+	set("gomodclone_prefill_success", "Whether the last prefill run succeeded.", success)
+	set("gomodclone_prefill_last_run_timestamp_seconds", "Time the last prefill run finished.", float64(time.Now().Unix()))
+	return r.WriteFile(file)
+}
+
+// writeZipReport writes the zip validation reports of a run as JSON.
+func writeZipReport(file string, reports []*modzip.Report) error {
+	if reports == nil {
+		reports = []*modzip.Report{}
+	}
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// retryPolicy builds the retry policy from the --attempts and --retry-* flags.
+func retryPolicy() retry.Policy {
+	p := retry.DefaultPolicy
+	p.Attempts = attempts
+	p.BaseDelay = retryDelay
+	p.MaxDelay = retryMax
+	if p.Attempts < 1 {
+		p.Attempts = 1
+	}
+	return p
+}
+
 func parseModulesList(filepath string) ([]gomod.ModuleSpec, error) {
 	content, err := os.ReadFile(filepath)
 	if err != nil {
@@ -201,6 +533,95 @@ func runServer() error {
 	log.Info("Port: %d", port)
 
 	// Create and start server
-	srv := server.NewServer(storageRoot, host, port)
+	opts := server.Options{
+		TLSCert:      tlsCert,
+		TLSKey:       tlsKey,
+		ClientCA:     clientCA,
+		RedirectHTTP: redirectTo,
+
+		ShutdownTimeout:   shutdownTimeout,
+		DrainDelay:        drainDelay,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		UploadTimeout:     uploadTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+		TrustProxy:        trustProxy,
+	}
+	if err := loadAuth(&opts); err != nil {
+		return err
+	}
+	if uploadACL != "" {
+		var pol *policy.Policy
+		if policyFile != "" {
+			var err error
+			if pol, err = policy.Load(policyFile); err != nil {
+				return fmt.Errorf("failed to load policy: %w", err)
+			}
+		}
+		opts.Policy = pol
+		opts.Uploads = packer.NewPackerWithOptions(storageRoot, packer.Options{
+			Policy:     pol,
+			Quarantine: quarantined,
+		})
+		log.Info("Uploads enabled")
+	}
+	if accessLog != "" {
+		l, err := accesslog.Open(accessLog, accessLogMaxSize, accessLogBackups)
+		if err != nil {
+			return fmt.Errorf("failed to open access log: %w", err)
+		}
+		defer l.Close()
+		opts.AccessLog = l
+		log.Info("Access log: %s", accessLog)
+	}
+	srv := server.NewServerWithOptions(storageRoot, host, port, opts)
 	return srv.Start()
 }
+
+// loadAuth sets up the server's authenticators and ACL from the auth flags.
+func loadAuth(opts *server.Options) error {
+	var chain auth.Chain
+	if tokensFile != "" {
+		tokens, err := auth.LoadTokens(tokensFile)
+		if err != nil {
+			return fmt.Errorf("failed to load tokens: %w", err)
+		}
+		chain = append(chain, tokens)
+	}
+	if htpasswd != "" {
+		h, err := auth.LoadHtpasswd(htpasswd)
+		if err != nil {
+			return fmt.Errorf("failed to load htpasswd: %w", err)
+		}
+		chain = append(chain, h)
+	}
+	if aclFile != "" {
+		if len(chain) == 0 {
+			return fmt.Errorf("--acl needs --auth-tokens or --htpasswd")
+		}
+		acl, err := auth.LoadACL(aclFile)
+		if err != nil {
+			return err
+		}
+		opts.ACL = acl
+	}
+	if uploadACL != "" {
+		if len(chain) == 0 {
+			return fmt.Errorf("--upload-acl needs --auth-tokens or --htpasswd")
+		}
+		acl, err := auth.LoadACL(uploadACL)
+		if err != nil {
+			return err
+		}
+		opts.UploadACL = acl
+	}
+	if len(chain) > 0 {
+		opts.Auth = chain
+		if tlsCert == "" {
+			log.Warn("Authentication is enabled without TLS; credentials are sent in the clear")
+		}
+	}
+	return nil
+}
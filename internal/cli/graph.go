@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/example/go-mod-clone/internal/graph"
+	"github.com/example/go-mod-clone/internal/resolver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphFormat string
+	graphOutput string
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the dependency graph of the last prefill run",
+	Long: `Print the module requirement graph recorded by the last prefill run that
+used the given work directory, as Graphviz DOT, JSON or go mod graph text.
+
+Example: go-mod-clone graph -w /var/cache/go-mod-clone -f dot | dot -Tsvg > graph.svg`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGraph()
+	},
+}
+
+func init() {
+	graphCmd.Flags().StringVarP(&workDir, "work-dir", "w", "", "Work directory of the prefill run (required)")
+	graphCmd.Flags().StringVarP(&graphFormat, "format", "f", "dot", "Output format (dot, json, modgraph)")
+	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "", "Write the graph to this file instead of stdout")
+
+	graphCmd.MarkFlagRequired("work-dir")
+
+	rootCmd.AddCommand(graphCmd)
+}
+
+// loadGraph reads the graph stored by the last prefill run in workDir.
+func loadGraph() (*graph.Graph, error) {
+	g, err := graph.Load(resolver.GraphFile(workDir))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no resolution graph in %s; run a prefill with --work-dir %s first", workDir, workDir)
+	}
+	return g, err
+}
+
+func runGraph() error {
+	g, err := loadGraph()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if graphOutput != "" {
+		f, err := os.Create(graphOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return g.Write(w, graphFormat)
+}
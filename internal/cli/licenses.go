@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/go-mod-clone/internal/license"
+	"github.com/example/go-mod-clone/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	licensesFormat  string
+	licensesFailBad bool
+)
+
+var licensesCmd = &cobra.Command{
+	Use:   "licenses",
+	Short: "Report the licenses of mirrored modules",
+	Long: `List the detected licenses of every module version in the storage root and
+flag versions whose license file was not recognized or is missing.
+
+Versions packed before license detection existed are classified on the fly.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLicenses()
+	},
+}
+
+func init() {
+	licensesCmd.Flags().StringVarP(&storageRoot, "storage-root", "s", "", "Module storage root directory (required)")
+	licensesCmd.Flags().StringVarP(&licensesFormat, "format", "f", "text", "Output format (text, json)")
+	licensesCmd.Flags().BoolVar(&licensesFailBad, "fail-on-unknown", false, "Exit with an error if any license is unknown or missing")
+
+	licensesCmd.MarkFlagRequired("storage-root")
+
+	rootCmd.AddCommand(licensesCmd)
+}
+
+// loadLicenseReport returns the stored license metadata of a version, or
+// classifies its zip when no metadata was stored.
+func loadLicenseReport(mod storage.Module, version string) (*license.Report, error) {
+	report, err := license.ReadMetadata(storage.VersionFile(mod.Dir, version, license.MetadataExt))
+	if err == nil {
+		return report, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return license.DetectZip(storage.VersionFile(mod.Dir, version, ".zip"), mod.Path, version)
+}
+
+func runLicenses() error {
+	modules, err := storage.ListModules(storageRoot)
+	if err != nil {
+		return fmt.Errorf("failed to list modules: %w", err)
+	}
+
+	var reports []*license.Report
+	flagged := 0
+	for _, mod := range modules {
+		for _, version := range mod.Versions {
+			report, err := loadLicenseReport(mod, version)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s@%s: %v\n", mod.Path, version, err)
+				continue
+			}
+			if report.Status != license.StatusDetected {
+				flagged++
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	switch licensesFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	case "text":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODULE\tVERSION\tLICENSES\tSTATUS")
+		for _, r := range reports {
+			licenses := strings.Join(r.Licenses, ", ")
+			if licenses == "" {
+				licenses = "-"
+			}
+			status := r.Status
+			if status != license.StatusDetected {
+				status = strings.ToUpper(status)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Path, r.Version, licenses, status)
+		}
+		tw.Flush()
+		fmt.Printf("\n%d module versions, %d with unknown or missing licenses\n", len(reports), flagged)
+	default:
+		return fmt.Errorf("unknown format %q (want text or json)", licensesFormat)
+	}
+
+	if licensesFailBad && flagged > 0 {
+		return fmt.Errorf("%d module versions have unknown or missing licenses", flagged)
+	}
+	return nil
+}
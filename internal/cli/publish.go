@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/packer"
+	"github.com/example/go-mod-clone/internal/policy"
+	"github.com/example/go-mod-clone/internal/quarantine"
+	"github.com/example/go-mod-clone/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// publishTokenEnv holds the upload token, so that it need not appear on the
+// command line.
+const publishTokenEnv = "GO_MOD_CLONE_TOKEN"
+
+var (
+	publishVersion string
+	publishURL     string
+	publishUser    string
+	publishToken   string
+	publishTimeout time.Duration
+)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish <dir>",
+	Short: "Publish a local module directory as a mirror version",
+	Long: `Package the module in dir as a version: a module zip built the way the go
+command builds it, the .mod file from dir/go.mod and an .info file. The version
+is packed into a storage root (--storage-root) or uploaded to a server started
+with --upload-acl (--to).
+
+Without --version the version is derived from the git commit checked out in
+dir: the commit's tag if it has one, otherwise a pseudo-version based on the
+latest tag the commit descends from, like the go command reports for it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPublish(args[0])
+	},
+}
+
+func init() {
+	publishCmd.Flags().StringVar(&publishVersion, "version", "", "Version to publish, e.g. v1.2.3; derived from git if empty")
+	publishCmd.Flags().StringVarP(&storageRoot, "storage-root", "s", "", "Pack the version into this storage root")
+	publishCmd.Flags().StringVar(&publishURL, "to", "", "Upload the version to the server at this URL, e.g. https://goproxy.example.com")
+	publishCmd.Flags().StringVar(&publishUser, "user", "", "User for basic auth with --to; without it the token is sent as a bearer token")
+	publishCmd.Flags().StringVar(&publishToken, "token", "", "Token or password for --to (default $"+publishTokenEnv+")")
+	publishCmd.Flags().DurationVar(&publishTimeout, "timeout", server.DefaultUploadTimeout, "Maximum time for the upload with --to, negative for no limit")
+	publishCmd.Flags().StringVar(&policyFile, "policy", "", "Policy file the version must satisfy when packing into --storage-root")
+	publishCmd.Flags().BoolVar(&quarantined, "quarantine", false, "Stage the version for approval when packing into --storage-root")
+	publishCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+
+	rootCmd.AddCommand(publishCmd)
+}
+
+func runPublish(dir string) error {
+	log.SetLevelFromString(logLevel)
+
+	if (storageRoot == "") == (publishURL == "") {
+		return fmt.Errorf("need exactly one of --storage-root and --to")
+	}
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	modPath := gomod.ModulePath(string(goMod))
+	if modPath == "" {
+		return fmt.Errorf("%s has no module directive", filepath.Join(dir, "go.mod"))
+	}
+
+	version := publishVersion
+	var commitTime time.Time
+	if version == "" {
+		if version, commitTime, err = gitVersion(dir, modPath); err != nil {
+			return err
+		}
+		log.Info("Derived version %s from git", version)
+	} else {
+		if !gomod.IsCanonicalVersion(version) {
+			return fmt.Errorf("invalid version %q: need a canonical semantic version such as v1.2.3", version)
+		}
+		// The commit time is the release time if dir is a checkout
+		if _, t, err := gitHead(dir); err == nil {
+			commitTime = t
+		}
+	}
+	if err := gomod.CheckPathMajor(modPath, version); err != nil {
+		return err
+	}
+	if commitTime.IsZero() {
+		commitTime = time.Now()
+	}
+
+	tmp, err := os.MkdirTemp("", "go-mod-clone-publish-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	mod := gomod.Module{
+		Path:     modPath,
+		Version:  version,
+		ZipFile:  filepath.Join(tmp, version+".zip"),
+		ModFile:  filepath.Join(tmp, version+".mod"),
+		InfoFile: filepath.Join(tmp, version+".info"),
+	}
+	if err := modzip.CreateFromDir(mod.ZipFile, dir, modPath, version); err != nil {
+		return fmt.Errorf("failed to create module zip: %w", err)
+	}
+	if err := os.WriteFile(mod.ModFile, goMod, 0644); err != nil {
+		return err
+	}
+	info, _ := json.Marshal(struct {
+		Version string
+		Time    time.Time
+	}{version, commitTime.UTC().Truncate(time.Second)})
+	if err := os.WriteFile(mod.InfoFile, info, 0644); err != nil {
+		return err
+	}
+
+	if publishURL != "" {
+		err = uploadModule(mod)
+	} else {
+		err = packModule(mod)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(modPath + "@" + version)
+	return nil
+}
+
+// packModule packs a module version into the storage root, refusing to
+// replace an existing version.
+func packModule(mod gomod.Module) error {
+	published := filepath.Join(storageRoot, filepath.FromSlash(mod.Path), "@v", mod.Version+".zip")
+	staged := filepath.Join(quarantine.AtVDir(storageRoot, mod.Path), mod.Version+".zip")
+	for _, existing := range []string{published, staged} {
+		if _, err := os.Stat(existing); err == nil {
+			return fmt.Errorf("%s@%s already exists in %s", mod.Path, mod.Version, storageRoot)
+		}
+	}
+
+	var pol *policy.Policy
+	if policyFile != "" {
+		var err error
+		if pol, err = policy.Load(policyFile); err != nil {
+			return fmt.Errorf("failed to load policy: %w", err)
+		}
+		violations := pol.Evaluate(policy.Subject{Path: mod.Path, Version: mod.Version})
+		for _, v := range violations {
+			log.Warn("Policy violation: %s", v)
+		}
+		if policy.Blocking(violations) {
+			return fmt.Errorf("blocked by policy: %s", violations[0].Reason)
+		}
+	}
+	p := packer.NewPackerWithOptions(storageRoot, packer.Options{Policy: pol, Quarantine: quarantined})
+	if err := p.Pack(mod); err != nil {
+		return fmt.Errorf("failed to pack %s@%s: %w", mod.Path, mod.Version, err)
+	}
+	if p.Staged() > 0 {
+		log.Info("Staged %s@%s for approval", mod.Path, mod.Version)
+	} else {
+		log.Info("Published %s@%s to %s", mod.Path, mod.Version, storageRoot)
+	}
+	zipHash, _ := modzip.HashZip(mod.ZipFile)
+	modHash, _ := modzip.HashGoMod(mod.ModFile)
+	logSum(mod, zipHash, modHash)
+	return nil
+}
+
+// logSum logs the go.sum lines of a published version.
+func logSum(mod gomod.Module, zipHash, modHash string) {
+	log.Info("go.sum: %s %s %s", mod.Path, mod.Version, zipHash)
+	log.Info("go.sum: %s %s/go.mod %s", mod.Path, mod.Version, modHash)
+}
+
+// uploadModule sends a module version to the upload API of a server.
+func uploadModule(mod gomod.Module) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ name, file string }{{"zip", mod.ZipFile}, {"mod", mod.ModFile}, {"info", mod.InfoFile}} {
+		if err := addFormFile(mw, part.name, part.file); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	u := strings.TrimSuffix(publishURL, "/") + "/api/v1/modules/" + mod.Path + "/@v/" + mod.Version
+	req, err := http.NewRequest(http.MethodPut, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	token := publishToken
+	if token == "" {
+		token = os.Getenv(publishTokenEnv)
+	}
+	switch {
+	case publishUser != "":
+		req.SetBasicAuth(publishUser, token)
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: max(publishTimeout, 0)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s@%s: %w", mod.Path, mod.Version, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var result struct {
+		Status    string `json:"status"`
+		ZipHash   string `json:"zip_hash"`
+		GoModHash string `json:"go_mod_hash"`
+		Error     string `json:"error"`
+	}
+	json.Unmarshal(data, &result)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		msg := result.Error
+		if msg == "" {
+			msg = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("failed to upload %s@%s: %s: %s", mod.Path, mod.Version, resp.Status, msg)
+	}
+	log.Info("Uploaded %s@%s to %s (%s)", mod.Path, mod.Version, publishURL, result.Status)
+	logSum(mod, result.ZipHash, result.GoModHash)
+	return nil
+}
+
+// addFormFile adds the contents of file to a multipart form as field name.
+func addFormFile(mw *multipart.Writer, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := mw.CreateFormFile(name, filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// gitVersion returns the version of the commit checked out in dir and its
+// commit time. A commit tagged with a version of the module has that
+// version; others get a pseudo-version based on the highest version tag
+// they descend from. Tags of modules in subdirectories carry the
+// subdirectory as prefix, such as sub/v1.0.0.
+func gitVersion(dir, modPath string) (string, time.Time, error) {
+	rev, commitTime, err := gitHead(dir)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("no --version given and %s is not a git checkout: %w", dir, err)
+	}
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if status, err := git(dir, "status", "--porcelain", "--", "."); err == nil && status != "" {
+		log.Warn("%s has uncommitted changes; they are included in the zip", dir)
+	}
+
+	tagged, err := git(dir, "tag", "--points-at", "HEAD", "--list", prefix+"v*")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if v := highestVersion(tagged, prefix, modPath); v != "" {
+		return v, commitTime, nil
+	}
+	merged, err := git(dir, "tag", "--merged", "HEAD", "--list", prefix+"v*")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	base := highestVersion(merged, prefix, modPath)
+	return gomod.PseudoVersion(gomod.PathMajor(modPath), base, commitTime, rev), commitTime, nil
+}
+
+// highestVersion returns the highest of the tags, one per line, that is a
+// canonical version of modPath once prefix is removed.
+func highestVersion(tags, prefix, modPath string) string {
+	best := ""
+	for _, tag := range strings.Fields(tags) {
+		v, ok := strings.CutPrefix(tag, prefix)
+		if !ok || !gomod.IsCanonicalVersion(v) || gomod.CheckPathMajor(modPath, v) != nil {
+			continue
+		}
+		if best == "" || gomod.CompareVersions(v, best) > 0 {
+			best = v
+		}
+	}
+	return best
+}
+
+// gitHead returns the revision and commit time of HEAD in dir.
+func gitHead(dir string) (string, time.Time, error) {
+	out, err := git(dir, "show", "-s", "--format=%H %ct", "HEAD")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	rev, ts, _ := strings.Cut(out, " ")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("unexpected git output %q", out)
+	}
+	return rev, time.Unix(sec, 0).UTC(), nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/auth"
+	"github.com/example/go-mod-clone/internal/packer"
+	"github.com/example/go-mod-clone/internal/server"
+)
+
+// gitRepo returns a git repository with a committed module example.com/pub,
+// and a function running git in it.
+func gitRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := git(dir, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	run("init", "-q")
+	writeModuleFile(t, dir, "go.mod", "module example.com/pub\n\ngo 1.21\n")
+	writeModuleFile(t, dir, "pub.go", "package pub\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	return dir, run
+}
+
+func writeModuleFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setPublishFlags sets the flags of the publish command for one test.
+func setPublishFlags(t *testing.T, version, root, to, token string) {
+	t.Helper()
+	oldVersion, oldRoot, oldURL, oldToken := publishVersion, storageRoot, publishURL, publishToken
+	oldUser, oldPolicy, oldQuarantined, oldLevel := publishUser, policyFile, quarantined, logLevel
+	oldTimeout := publishTimeout
+	t.Cleanup(func() {
+		publishVersion, storageRoot, publishURL, publishToken = oldVersion, oldRoot, oldURL, oldToken
+		publishUser, policyFile, quarantined, logLevel = oldUser, oldPolicy, oldQuarantined, oldLevel
+		publishTimeout = oldTimeout
+	})
+	publishVersion, storageRoot, publishURL, publishToken = version, root, to, token
+	publishUser, policyFile, quarantined, logLevel = "", "", false, "error"
+	publishTimeout = server.DefaultUploadTimeout
+}
+
+func TestGitVersion(t *testing.T) {
+	dir, run := gitRepo(t)
+
+	// A tagged commit has the tag's version; tags of other major versions
+	// are not versions of the module
+	run("tag", "v1.2.0")
+	run("tag", "v2.0.0")
+	if v, _, err := gitVersion(dir, "example.com/pub"); err != nil || v != "v1.2.0" {
+		t.Errorf("tagged HEAD: gitVersion = %q, %v, want v1.2.0", v, err)
+	}
+
+	// A later commit gets a pseudo-version above the tag
+	writeModuleFile(t, dir, "more.go", "package pub\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "more")
+	rev := run("rev-parse", "HEAD")
+	v, _, err := gitVersion(dir, "example.com/pub")
+	if err != nil || !strings.HasPrefix(v, "v1.2.1-0.") || !strings.HasSuffix(v, "-"+rev[:12]) {
+		t.Errorf("untagged commit: gitVersion = %q, %v, want a v1.2.1-0 pseudo-version of %s", v, err, rev[:12])
+	}
+
+	// Tags of a module in a subdirectory carry the directory as prefix
+	writeModuleFile(t, dir, "sub/go.mod", "module example.com/pub/sub\n\ngo 1.21\n")
+	writeModuleFile(t, dir, "sub/sub.go", "package sub\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "sub")
+	run("tag", "sub/v0.3.0")
+	if v, _, err := gitVersion(filepath.Join(dir, "sub"), "example.com/pub/sub"); err != nil || v != "v0.3.0" {
+		t.Errorf("sub/v0.3.0: gitVersion = %q, %v, want v0.3.0", v, err)
+	}
+	if v, _, err := gitVersion(dir, "example.com/pub"); err != nil || !strings.HasPrefix(v, "v1.2.1-0.") {
+		t.Errorf("root module with a subdirectory tag: gitVersion = %q, %v, want a v1.2.1-0 pseudo-version", v, err)
+	}
+}
+
+func TestHighestVersion(t *testing.T) {
+	tags := "v1.0.0\nv1.10.0\nv1.9.0\nv2.0.0\nv1.11.0-rc.1\nvnext\nsub/v0.3.0\n"
+	tests := []struct {
+		prefix, modPath, want string
+	}{
+		{"", "example.com/pub", "v1.11.0-rc.1"},
+		{"", "example.com/pub/v2", "v2.0.0"},
+		{"sub/", "example.com/pub/sub", "v0.3.0"},
+		{"other/", "example.com/pub/other", ""},
+	}
+	for _, tt := range tests {
+		if got := highestVersion(tags, tt.prefix, tt.modPath); got != tt.want {
+			t.Errorf("highestVersion(%q, %s) = %q, want %q", tt.prefix, tt.modPath, got, tt.want)
+		}
+	}
+}
+
+func TestPublish_StorageRoot(t *testing.T) {
+	dir, _ := gitRepo(t)
+	root := t.TempDir()
+	setPublishFlags(t, "v1.0.0", root, "", "")
+
+	if err := runPublish(dir); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	for _, ext := range []string{".info", ".mod", ".zip"} {
+		if _, err := os.Stat(filepath.Join(root, "example.com", "pub", "@v", "v1.0.0"+ext)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Published and staged versions are never replaced
+	if err := runPublish(dir); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("second publish = %v, want an already exists error", err)
+	}
+	publishVersion, quarantined = "v1.1.0", true
+	if err := runPublish(dir); err != nil {
+		t.Fatalf("staged publish failed: %v", err)
+	}
+	quarantined = false
+	if err := runPublish(dir); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("publish of a staged version = %v, want an already exists error", err)
+	}
+}
+
+func TestPublish_Upload(t *testing.T) {
+	dir, _ := gitRepo(t)
+	root := t.TempDir()
+	tokens := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokens, []byte("ci secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authn, err := auth.LoadTokens(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.NewServerWithOptions(root, "localhost", 0, server.Options{
+		Auth:      authn,
+		Uploads:   packer.NewPacker(root),
+		UploadACL: &auth.ACL{Rules: []auth.ACLRule{{Prefix: "example.com/pub", Users: []string{"ci"}}}},
+	}).Handler())
+	defer srv.Close()
+
+	setPublishFlags(t, "v1.0.0", "", srv.URL, "wrong")
+	if err := runPublish(dir); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("upload with a wrong token = %v, want 401", err)
+	}
+
+	publishToken = "secret"
+	if err := runPublish(dir); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "example.com", "pub", "@v", "v1.0.0.zip")); err != nil {
+		t.Errorf("uploaded version not published: %v", err)
+	}
+	if err := runPublish(dir); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("second upload = %v, want 409", err)
+	}
+
+	// A server that does not answer fails the upload after --timeout
+	stuck := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stuck
+	}))
+	defer hung.Close()
+	defer close(stuck)
+	publishURL, publishVersion, publishTimeout = hung.URL, "v1.1.0", 100*time.Millisecond
+	if err := runPublish(dir); err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Errorf("upload to a hung server = %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/go-mod-clone/internal/packer"
+	"github.com/example/go-mod-clone/internal/quarantine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	approver      string
+	approveReason string
+	showAuditLog  bool
+)
+
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "List module versions awaiting approval",
+	Long: `List the versions staged by a prefill run with --quarantine. The server does
+not serve them until they are approved.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runQuarantine()
+	},
+}
+
+var approveCmd = &cobra.Command{
+	Use:   "approve <module@version>...",
+	Short: "Publish quarantined module versions",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runReview(args, true)
+	},
+}
+
+var rejectCmd = &cobra.Command{
+	Use:   "reject <module@version>...",
+	Short: "Delete quarantined module versions",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runReview(args, false)
+	},
+}
+
+func init() {
+	quarantineCmd.Flags().StringVarP(&storageRoot, "storage-root", "s", "", "Module storage root directory (required)")
+	quarantineCmd.Flags().BoolVar(&showAuditLog, "log", false, "Print the audit log instead of the pending versions")
+	quarantineCmd.MarkFlagRequired("storage-root")
+
+	for _, cmd := range []*cobra.Command{approveCmd, rejectCmd} {
+		cmd.Flags().StringVarP(&storageRoot, "storage-root", "s", "", "Module storage root directory (required)")
+		cmd.Flags().StringVar(&approver, "user", quarantine.CurrentUser(), "Name recorded in the audit log")
+		cmd.Flags().StringVar(&approveReason, "reason", "", "Reason recorded in the audit log")
+		cmd.MarkFlagRequired("storage-root")
+	}
+
+	rootCmd.AddCommand(quarantineCmd, approveCmd, rejectCmd)
+}
+
+func runQuarantine() error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	if showAuditLog {
+		events, err := quarantine.ReadLog(storageRoot)
+		if err != nil {
+			return fmt.Errorf("failed to read audit log: %w", err)
+		}
+		fmt.Fprintln(tw, "TIME\tACTION\tMODULE\tVERSION\tUSER\tREASON")
+		for _, e := range events {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Format("2006-01-02T15:04:05Z07:00"), e.Action, e.Path, e.Version, e.User, e.Reason)
+		}
+		return nil
+	}
+
+	pending, err := quarantine.Pending(storageRoot)
+	if err != nil {
+		return fmt.Errorf("failed to list quarantine: %w", err)
+	}
+	fmt.Fprintln(tw, "MODULE\tVERSION")
+	for _, mod := range pending {
+		for _, version := range mod.Versions {
+			fmt.Fprintf(tw, "%s\t%s\n", mod.Path, version)
+		}
+	}
+	return nil
+}
+
+// runReview approves or rejects each module@version argument, continuing
+// past failures so that one bad argument does not hold up the rest.
+func runReview(args []string, approve bool) error {
+	p := packer.NewPacker(storageRoot)
+	failed := 0
+	for _, arg := range args {
+		i := strings.LastIndex(arg, "@")
+		if i <= 0 || i == len(arg)-1 {
+			fmt.Fprintf(os.Stderr, "%s: want module@version\n", arg)
+			failed++
+			continue
+		}
+		modPath, version := arg[:i], arg[i+1:]
+
+		var err error
+		action := "Approved"
+		if approve {
+			err = p.Approve(modPath, version, approver, approveReason)
+		} else {
+			action = "Rejected"
+			err = p.Reject(modPath, version, approver, approveReason)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", arg, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s\n", action, arg)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d module versions failed", failed, len(args))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/graph"
+	"github.com/example/go-mod-clone/internal/license"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/packer"
+	"github.com/example/go-mod-clone/internal/sbom"
+	"github.com/example/go-mod-clone/internal/storage"
+)
+
+// writeSBOMs writes CycloneDX and SPDX documents describing the packed
+// modules, with hashes and licenses taken from the storage root so that the
+// SBOM matches what is actually transferred.
+func writeSBOMs(dir, name string, packed []gomod.Module, p *packer.Packer, g *graph.Graph) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	components := make([]sbom.Component, 0, len(packed))
+	for _, mod := range packed {
+		atVDir := p.AtVDir(mod.Path)
+		c := sbom.Component{Path: mod.Path, Version: mod.Version}
+
+		zipFile := storage.VersionFile(atVDir, mod.Version, ".zip")
+		var err error
+		if c.Hash, err = modzip.HashZip(zipFile); err != nil {
+			log.Warn("SBOM: no zip hash for %s@%s: %v", mod.Path, mod.Version, err)
+		}
+		if c.ZipSHA256, err = modzip.SHA256File(zipFile); err != nil {
+			log.Warn("SBOM: no zip checksum for %s@%s: %v", mod.Path, mod.Version, err)
+		}
+		if c.GoModHash, err = modzip.HashGoMod(storage.VersionFile(atVDir, mod.Version, ".mod")); err != nil {
+			log.Warn("SBOM: no go.mod hash for %s@%s: %v", mod.Path, mod.Version, err)
+		}
+		if report, err := license.ReadMetadata(storage.VersionFile(atVDir, mod.Version, license.MetadataExt)); err == nil {
+			c.Licenses = report.Licenses
+		}
+		components = append(components, c)
+	}
+
+	bom := sbom.New(filepath.Base(name), components, g)
+	stamp := bom.Timestamp.Format("20060102T150405Z")
+
+	outputs := []struct {
+		file  string
+		write func(*os.File) error
+	}{
+		{fmt.Sprintf("sbom-%s.cdx.json", stamp), func(f *os.File) error { return bom.WriteCycloneDX(f) }},
+		{fmt.Sprintf("sbom-%s.spdx.json", stamp), func(f *os.File) error { return bom.WriteSPDX(f) }},
+	}
+	for _, out := range outputs {
+		path := filepath.Join(dir, out.file)
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = out.write(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		log.Info("Wrote SBOM %s (%d components)", path, len(components))
+	}
+	return nil
+}
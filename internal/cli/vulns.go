@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/example/go-mod-clone/internal/storage"
+	"github.com/example/go-mod-clone/internal/vulndb"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vulnsFormat   string
+	vulnsFailVuln bool
+)
+
+var vulnsCmd = &cobra.Command{
+	Use:   "vulns",
+	Short: "Report known vulnerabilities in mirrored modules",
+	Long: `Match every module version in the storage root against the vulnerability
+database mirrored with --vulndb, without network access.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runVulns()
+	},
+}
+
+func init() {
+	vulnsCmd.Flags().StringVarP(&storageRoot, "storage-root", "s", "", "Module storage root directory (required)")
+	vulnsCmd.Flags().StringVarP(&vulnsFormat, "format", "f", "text", "Output format (text, json)")
+	vulnsCmd.Flags().BoolVar(&vulnsFailVuln, "fail-on-vuln", false, "Exit with an error if any mirrored version is vulnerable")
+
+	vulnsCmd.MarkFlagRequired("storage-root")
+
+	rootCmd.AddCommand(vulnsCmd)
+}
+
+func runVulns() error {
+	modules, err := storage.ListModules(storageRoot)
+	if err != nil {
+		return fmt.Errorf("failed to list modules: %w", err)
+	}
+	findings, err := vulndb.Scan(vulndb.Dir(storageRoot), modules)
+	if err != nil {
+		return err
+	}
+
+	switch vulnsFormat {
+	case "json":
+		if findings == nil {
+			findings = []vulndb.Finding{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return err
+		}
+	case "text":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODULE\tVERSION\tID\tFIXED\tSUMMARY")
+		for _, f := range findings {
+			fixed := f.Fixed
+			if fixed == "" {
+				fixed = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Path, f.Version, f.ID, fixed, f.Summary)
+		}
+		tw.Flush()
+		fmt.Printf("\n%d vulnerable module versions\n", len(findings))
+	default:
+		return fmt.Errorf("unknown format %q (want text or json)", vulnsFormat)
+	}
+
+	if vulnsFailVuln && len(findings) > 0 {
+		return fmt.Errorf("%d mirrored module versions are vulnerable", len(findings))
+	}
+	return nil
+}
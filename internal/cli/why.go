@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var whyMaxChains int
+
+var whyCmd = &cobra.Command{
+	Use:   "why <module>[@version]",
+	Short: "Explain why a module is in the mirror",
+	Long: `Print the shortest requirement chains from a module listed in modules.txt
+down to the given module, using the resolution graph recorded by the last
+prefill run that used the given work directory.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWhy(args[0])
+	},
+}
+
+func init() {
+	whyCmd.Flags().StringVarP(&workDir, "work-dir", "w", "", "Work directory of the prefill run (required)")
+	whyCmd.Flags().IntVar(&whyMaxChains, "max-chains", 10, "Maximum number of chains to print per module version (0 for all)")
+
+	whyCmd.MarkFlagRequired("work-dir")
+
+	rootCmd.AddCommand(whyCmd)
+}
+
+func runWhy(target string) error {
+	g, err := loadGraph()
+	if err != nil {
+		return err
+	}
+
+	nodes := g.Match(target)
+	if len(nodes) == 0 {
+		return fmt.Errorf("%s is not in the resolution graph", target)
+	}
+
+	for i, node := range nodes {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("# %s\n", node)
+
+		chains := g.ShortestChains(node, whyMaxChains)
+		if chains == nil {
+			fmt.Printf("(%s is not required by any module in modules.txt)\n", node)
+			continue
+		}
+		for j, chain := range chains {
+			if j > 0 {
+				fmt.Println()
+			}
+			fmt.Println(strings.Join(chain, "\n"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Package crosscheck compares the go.mod and zip hashes of a module version
+// across several independent upstreams, so that a single compromised proxy
+// cannot slip altered content into the mirror.
+package crosscheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/retry"
+	"github.com/example/go-mod-clone/internal/upstream"
+)
+
+// LocalSource names the copy being packed in Mismatch.Hashes.
+const LocalSource = "local"
+
+// sumdbPrefix marks a checksum database in a source list.
+const sumdbPrefix = "sumdb:"
+
+// Source is an upstream to compare against: a module proxy, or a checksum
+// database such as https://sum.golang.org.
+type Source struct {
+	URL   string
+	SumDB bool
+}
+
+func (s Source) String() string {
+	if s.SumDB {
+		return sumdbPrefix + s.URL
+	}
+	return s.URL
+}
+
+// ParseSources parses a comma-separated list of proxy URLs and
+// "sumdb:<url>" checksum databases. At least two sources are required.
+func ParseSources(list string) ([]Source, error) {
+	var sources []Source
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		s := Source{URL: item}
+		if strings.HasPrefix(item, sumdbPrefix) {
+			s = Source{URL: strings.TrimPrefix(item, sumdbPrefix), SumDB: true}
+		}
+		if !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+			return nil, fmt.Errorf("invalid source %q: want an http(s) URL", item)
+		}
+		s.URL = strings.TrimSuffix(s.URL, "/")
+		sources = append(sources, s)
+	}
+	if len(sources) < 2 {
+		return nil, fmt.Errorf("need at least two sources to cross-check, got %d", len(sources))
+	}
+	return sources, nil
+}
+
+// Hashes are the "h1:" hashes of a version's go.mod and zip. An empty hash
+// means the source did not provide that file.
+type Hashes struct {
+	Mod string
+	Zip string
+}
+
+// Mismatch is a file whose hash differs between sources.
+type Mismatch struct {
+	Path    string            `json:"path"`
+	Version string            `json:"version"`
+	File    string            `json:"file"`   // "go.mod" or "zip"
+	Hashes  map[string]string `json:"hashes"` // hash reported by each source
+}
+
+func (m Mismatch) String() string {
+	var parts []string
+	for _, src := range m.sources() {
+		parts = append(parts, src+"="+m.Hashes[src])
+	}
+	return fmt.Sprintf("%s@%s %s: %s", m.Path, m.Version, m.File, strings.Join(parts, " "))
+}
+
+func (m Mismatch) sources() []string {
+	sources := make([]string, 0, len(m.Hashes))
+	for src := range m.Hashes {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Verifier fetches hashes from the configured sources.
+type Verifier struct {
+	client  *upstream.Client
+	sources []Source
+}
+
+func NewVerifier(client *upstream.Client, sources []Source) *Verifier {
+	return &Verifier{client: client, sources: sources}
+}
+
+// Verify compares the local hashes of path@version with those of every
+// source and returns the files on which they disagree. Sources that do not
+// have the version, such as a checksum database for a private module, are
+// left out of the comparison; a source that cannot be reached is an error.
+func (v *Verifier) Verify(modPath, version string, local Hashes) ([]Mismatch, error) {
+	mods := map[string]string{}
+	zips := map[string]string{}
+	add := func(src string, h Hashes) {
+		if h.Mod != "" {
+			mods[src] = h.Mod
+		}
+		if h.Zip != "" {
+			zips[src] = h.Zip
+		}
+	}
+	add(LocalSource, local)
+
+	answered := 0
+	for _, src := range v.sources {
+		var h Hashes
+		var err error
+		if src.SumDB {
+			h, err = v.lookupSumDB(src.URL, modPath, version)
+		} else {
+			h, err = v.fetchProxy(src.URL, modPath, version)
+		}
+		if isMissing(err) {
+			log.Debug("Cross-check: %s does not have %s@%s", src, modPath, version)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cross-check with %s: %w", src, err)
+		}
+		add(src.String(), h)
+		answered++
+	}
+	if answered < 2 {
+		log.Warn("Cross-check: only %d of %d sources have %s@%s", answered, len(v.sources), modPath, version)
+	}
+
+	var mismatches []Mismatch
+	for _, c := range []struct {
+		file   string
+		hashes map[string]string
+	}{{"go.mod", mods}, {"zip", zips}} {
+		if distinct(c.hashes) > 1 {
+			mismatches = append(mismatches, Mismatch{Path: modPath, Version: version, File: c.file, Hashes: c.hashes})
+		}
+	}
+	return mismatches, nil
+}
+
+func distinct(hashes map[string]string) int {
+	seen := make(map[string]bool)
+	for _, h := range hashes {
+		seen[h] = true
+	}
+	return len(seen)
+}
+
+// isMissing reports whether err means the source does not have a version.
+func isMissing(err error) bool {
+	var se *retry.StatusError
+	return errors.As(err, &se) && (se.StatusCode == http.StatusNotFound || se.StatusCode == http.StatusGone)
+}
+
+// versionURL returns the URL of a version's file in a proxy or checksum
+// database, with the path and version escaped as the protocols require.
+func versionURL(base, modPath, version, format string) (string, error) {
+	escPath, err := gomod.EscapePath(modPath)
+	if err != nil {
+		return "", err
+	}
+	escVersion, err := gomod.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return base + fmt.Sprintf(format, escPath, escVersion), nil
+}
+
+// fetchProxy downloads the go.mod and zip of a version from a module proxy
+// and hashes them.
+func (v *Verifier) fetchProxy(base, modPath, version string) (Hashes, error) {
+	var h Hashes
+	modURL, err := versionURL(base, modPath, version, "/%s/@v/%s.mod")
+	if err != nil {
+		return h, err
+	}
+	data, err := v.client.Get(modURL)
+	if err != nil {
+		return h, err
+	}
+	if h.Mod, err = modzip.HashGoModBytes(data); err != nil {
+		return h, err
+	}
+
+	zipURL, _ := versionURL(base, modPath, version, "/%s/@v/%s.zip")
+	if data, err = v.client.Get(zipURL); err != nil {
+		return h, err
+	}
+	if h.Zip, err = modzip.HashZipBytes(data); err != nil {
+		return h, fmt.Errorf("invalid zip from %s: %w", zipURL, err)
+	}
+	return h, nil
+}
+
+// lookupSumDB reads the go.sum lines of a version from a checksum database.
+// The signed tree head is not verified; the database only serves as an
+// independent second opinion.
+func (v *Verifier) lookupSumDB(base, modPath, version string) (Hashes, error) {
+	var h Hashes
+	url, err := versionURL(base, modPath, version, "/lookup/%s@%s")
+	if err != nil {
+		return h, err
+	}
+	data, err := v.client.Get(url)
+	if err != nil {
+		return h, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != modPath {
+			continue
+		}
+		switch fields[1] {
+		case version:
+			h.Zip = fields[2]
+		case version + "/go.mod":
+			h.Mod = fields[2]
+		}
+	}
+	if h.Mod == "" && h.Zip == "" {
+		return h, fmt.Errorf("no hashes for %s@%s in %s", modPath, version, url)
+	}
+	return h, nil
+}
+
+// WriteReport writes mismatches as JSON to file.
+func WriteReport(file string, mismatches []Mismatch) error {
+	if mismatches == nil {
+		mismatches = []Mismatch{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Mismatches []Mismatch `json:"mismatches"`
+	}{mismatches}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,133 @@
+package crosscheck
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/retry"
+	"github.com/example/go-mod-clone/internal/upstream"
+)
+
+const (
+	testPath    = "example.com/a"
+	testVersion = "v1.0.0"
+	testGoMod   = "module example.com/a\n"
+)
+
+func moduleZip(t *testing.T, source string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"go.mod": testGoMod, "a.go": source} {
+		w, err := zw.Create(testPath + "@" + testVersion + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// fixtureProxy serves one module version with the given zip.
+func fixtureProxy(t *testing.T, zipData []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/example.com/a/@v/v1.0.0.mod", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testGoMod))
+	})
+	mux.HandleFunc("/example.com/a/@v/v1.0.0.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(zipData)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func localHashes(t *testing.T, zipData []byte) Hashes {
+	t.Helper()
+	modHash, err := modzip.HashGoModBytes([]byte(testGoMod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipHash, err := modzip.HashZipBytes(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Hashes{Mod: modHash, Zip: zipHash}
+}
+
+func TestVerify_Proxies(t *testing.T) {
+	genuine := moduleZip(t, "package a\n")
+	tampered := moduleZip(t, "package a\n\nfunc init() { steal() }\n")
+	good := fixtureProxy(t, genuine)
+	evil := fixtureProxy(t, tampered)
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	client := upstream.NewClient(retry.Policy{Attempts: 1})
+	local := localHashes(t, genuine)
+
+	v := NewVerifier(client, []Source{{URL: good.URL}, {URL: missing.URL}})
+	if mismatches, err := v.Verify(testPath, testVersion, local); err != nil || len(mismatches) != 0 {
+		t.Errorf("Agreeing sources: mismatches %v, err %v", mismatches, err)
+	}
+
+	v = NewVerifier(client, []Source{{URL: good.URL}, {URL: evil.URL}})
+	mismatches, err := v.Verify(testPath, testVersion, local)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].File != "zip" {
+		t.Fatalf("Got mismatches %v, want one for the zip", mismatches)
+	}
+	hashes := mismatches[0].Hashes
+	if hashes[good.URL] != local.Zip || hashes[evil.URL] != localHashes(t, tampered).Zip || hashes[LocalSource] != local.Zip {
+		t.Errorf("Mismatch does not record every source's hash: %v", hashes)
+	}
+}
+
+func TestVerify_SumDB(t *testing.T) {
+	genuine := moduleZip(t, "package a\n")
+	local := localHashes(t, genuine)
+	sumdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/lookup/example.com/a@v1.0.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("1234\nexample.com/a v1.0.0 h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n" +
+			"example.com/a v1.0.0/go.mod " + local.Mod + "\n\ngo.sum database tree\n1234\n"))
+	}))
+	defer sumdb.Close()
+
+	client := upstream.NewClient(retry.Policy{Attempts: 1})
+	v := NewVerifier(client, []Source{{URL: fixtureProxy(t, genuine).URL}, {URL: sumdb.URL, SumDB: true}})
+	mismatches, err := v.Verify(testPath, testVersion, local)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].File != "zip" || len(mismatches[0].Hashes) != 3 {
+		t.Errorf("Got mismatches %v, want the sumdb zip hash to disagree", mismatches)
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources("https://proxy.golang.org/, sumdb:https://sum.golang.org")
+	if err != nil {
+		t.Fatalf("ParseSources failed: %v", err)
+	}
+	if len(sources) != 2 || sources[0].URL != "https://proxy.golang.org" || !sources[1].SumDB {
+		t.Errorf("Unexpected sources %+v", sources)
+	}
+	if _, err := ParseSources("https://proxy.golang.org"); err == nil {
+		t.Error("A single source should be rejected")
+	}
+	if _, err := ParseSources("proxy.golang.org,https://goproxy.io"); err == nil {
+		t.Error("A source without scheme should be rejected")
+	}
+}
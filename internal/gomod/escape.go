@@ -0,0 +1,61 @@
+package gomod
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapePath returns the case-encoded form of a module path used by the
+// module cache and the GOPROXY protocol: every upper-case letter is replaced
+// by an exclamation mark followed by the lower-case letter.
+func EscapePath(path string) (string, error) {
+	return escapeString(path)
+}
+
+// EscapeVersion is EscapePath for versions.
+func EscapeVersion(version string) (string, error) {
+	return escapeString(version)
+}
+
+func escapeString(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range s {
+		if r == '!' || r >= utf8.RuneSelf {
+			return "", fmt.Errorf("invalid character %q in %q", r, s)
+		}
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			b.WriteRune(r + 'a' - 'A')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
+
+// UnescapePath reverses EscapePath.
+func UnescapePath(escaped string) (string, error) {
+	var b strings.Builder
+	bang := false
+	for _, r := range escaped {
+		switch {
+		case bang:
+			if r < 'a' || r > 'z' {
+				return "", fmt.Errorf("invalid escape in %q", escaped)
+			}
+			b.WriteRune(r - 'a' + 'A')
+			bang = false
+		case r == '!':
+			bang = true
+		case 'A' <= r && r <= 'Z':
+			return "", fmt.Errorf("unescaped upper-case letter in %q", escaped)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if bang {
+		return "", fmt.Errorf("trailing escape in %q", escaped)
+	}
+	return b.String(), nil
+}
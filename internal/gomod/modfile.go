@@ -0,0 +1,67 @@
+package gomod
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseRequires returns the require directives of a go.mod file, both the
+// single-line and the parenthesized block form. Other directives are ignored.
+func ParseRequires(content string) ([]ModuleSpec, error) {
+	var requires []ModuleSpec
+	inBlock := false
+
+	for i, line := range strings.Split(content, "\n") {
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if inBlock {
+			if fields[0] == ")" {
+				inBlock = false
+				continue
+			}
+		} else {
+			if fields[0] != "require" {
+				continue
+			}
+			fields = fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				inBlock = true
+				continue
+			}
+		}
+
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: malformed require: %q", i+1, strings.TrimSpace(line))
+		}
+		requires = append(requires, ModuleSpec{
+			Path:    strings.Trim(fields[0], `"`),
+			Version: strings.Trim(fields[1], `"`),
+		})
+	}
+
+	if inBlock {
+		return nil, fmt.Errorf("unterminated require block")
+	}
+	return requires, nil
+}
+
+// ModulePath returns the path in the module directive of a go.mod file, or
+// "" if there is none.
+func ModulePath(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`")
+		}
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package gomod
 
 import (
 	"testing"
+	"time"
 )
 
 func TestParseModulesList(t *testing.T) {
@@ -104,3 +105,166 @@ google.golang.org/grpc`
 		t.Errorf("Second spec incorrect: %+v", specs[1])
 	}
 }
+
+func TestParseRequires(t *testing.T) {
+	content := `module example.com/app
+
+go 1.21
+
+require github.com/spf13/cobra v1.7.0
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	// a comment inside the block
+	github.com/spf13/pflag v1.0.5 // indirect
+)
+
+replace example.com/old => example.com/new v1.0.0
+`
+	requires, err := ParseRequires(content)
+	if err != nil {
+		t.Fatalf("ParseRequires failed: %v", err)
+	}
+
+	want := []ModuleSpec{
+		{Path: "github.com/spf13/cobra", Version: "v1.7.0"},
+		{Path: "github.com/inconshreveable/mousetrap", Version: "v1.1.0"},
+		{Path: "github.com/spf13/pflag", Version: "v1.0.5"},
+	}
+	if len(requires) != len(want) {
+		t.Fatalf("Got %d requires, want %d: %+v", len(requires), len(want), requires)
+	}
+	for i := range want {
+		if requires[i] != want[i] {
+			t.Errorf("Require %d: got %+v, want %+v", i, requires[i], want[i])
+		}
+	}
+
+	if _, err := ParseRequires("require (\n\tgithub.com/a/b v1.0.0\n"); err == nil {
+		t.Error("Expected error for unterminated require block")
+	}
+}
+
+func TestIsCanonicalVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"v1.9.1", true},
+		{"v1.0.0-beta.1", true},
+		{"v2.0.0+incompatible", true},
+		{"v0.0.0-20230101120000-abcdef123456", true},
+		{"v1", false},
+		{"v1.2", false},
+		{"v01.2.3", false},
+		{"latest", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := IsCanonicalVersion(tt.version); got != tt.want {
+				t.Errorf("IsCanonicalVersion(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	escaped, err := EscapePath("github.com/BurntSushi/toml")
+	if err != nil {
+		t.Fatalf("EscapePath failed: %v", err)
+	}
+	if escaped != "github.com/!burnt!sushi/toml" {
+		t.Errorf("EscapePath got %q", escaped)
+	}
+
+	path, err := UnescapePath(escaped)
+	if err != nil {
+		t.Fatalf("UnescapePath failed: %v", err)
+	}
+	if path != "github.com/BurntSushi/toml" {
+		t.Errorf("UnescapePath got %q", path)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3", "v1.10.0", -1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v1.0.0-alpha", "v1.0.0", -1},
+		{"v1.0.0-alpha.2", "v1.0.0-alpha.10", -1},
+		{"v1.0.0-alpha.beta", "v1.0.0-alpha.1", 1},
+		{"v1.0.0+meta", "v1.0.0", 0},
+		{"latest", "v0.0.1", -1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	for content, want := range map[string]string{
+		"// comment\nmodule example.com/m // trailing\n\ngo 1.21\n": "example.com/m",
+		"module \"example.com/quoted\"\n":                           "example.com/quoted",
+		"go 1.21\n":                                                 "",
+	} {
+		if got := ModulePath(content); got != want {
+			t.Errorf("ModulePath(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestCheckPathMajor(t *testing.T) {
+	tests := []struct {
+		path, version string
+		ok            bool
+	}{
+		{"example.com/m", "v0.1.0", true},
+		{"example.com/m", "v1.2.3", true},
+		{"example.com/m", "v2.0.0", false},
+		{"example.com/m", "v2.0.0+incompatible", true},
+		{"example.com/m/v2", "v2.1.0", true},
+		{"example.com/m/v2", "v1.0.0", false},
+		{"example.com/m/v2", "v2.0.0+incompatible", false},
+		{"example.com/m/v1", "v1.0.0", true},
+		{"gopkg.in/yaml.v3", "v3.0.1", true},
+		{"gopkg.in/yaml.v3", "v2.4.0", false},
+	}
+	for _, tt := range tests {
+		if err := CheckPathMajor(tt.path, tt.version); (err == nil) != tt.ok {
+			t.Errorf("CheckPathMajor(%q, %q) = %v, want ok %v", tt.path, tt.version, err, tt.ok)
+		}
+	}
+}
+
+func TestPseudoVersion(t *testing.T) {
+	commit := time.Date(2024, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+	const rev = "0123456789abcdef0123"
+	tests := []struct {
+		major, base, want string
+	}{
+		{"", "", "v0.0.0-20240304040607-0123456789ab"},
+		{"v2", "", "v2.0.0-20240304040607-0123456789ab"},
+		{"", "v1.2.3", "v1.2.4-0.20240304040607-0123456789ab"},
+		{"", "v1.2.9", "v1.2.10-0.20240304040607-0123456789ab"},
+		{"", "v1.3.0-rc.1", "v1.3.0-rc.1.0.20240304040607-0123456789ab"},
+		{"", "v2.0.0+incompatible", "v2.0.1-0.20240304040607-0123456789ab+incompatible"},
+	}
+	for _, tt := range tests {
+		got := PseudoVersion(tt.major, tt.base, commit, rev)
+		if got != tt.want {
+			t.Errorf("PseudoVersion(%q, %q) = %q, want %q", tt.major, tt.base, got, tt.want)
+		}
+		if !IsCanonicalVersion(got) {
+			t.Errorf("PseudoVersion(%q, %q) = %q is not canonical", tt.major, tt.base, got)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package gomod
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IsCanonicalVersion reports whether version is a complete semantic version
+// such as v1.2.3, v1.2.3-pre or v2.0.0+incompatible, as opposed to a query
+// like "latest", "v1" or a branch name.
+func IsCanonicalVersion(version string) bool {
+	_, ok := parseSemver(version)
+	return ok
+}
+
+type semver struct {
+	major, minor, patch string
+	prerelease          string
+	build               string
+}
+
+func parseSemver(v string) (semver, bool) {
+	var sv semver
+	if !strings.HasPrefix(v, "v") {
+		return sv, false
+	}
+	v = v[1:]
+
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		sv.build = v[i:]
+		v = v[:i]
+		if !isIdentList(sv.build[1:]) {
+			return sv, false
+		}
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		sv.prerelease = v[i:]
+		v = v[:i]
+		if !isIdentList(sv.prerelease[1:]) {
+			return sv, false
+		}
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return sv, false
+	}
+	for _, p := range parts {
+		if !isNumber(p) {
+			return sv, false
+		}
+	}
+	sv.major, sv.minor, sv.patch = parts[0], parts[1], parts[2]
+	return sv, true
+}
+
+func isNumber(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentList(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for i := 0; i < len(id); i++ {
+			c := id[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CompareVersions compares two canonical versions by semantic version
+// precedence, returning -1, 0 or +1. Build metadata is ignored. A version
+// that is not canonical sorts before every canonical one.
+func CompareVersions(a, b string) int {
+	va, okA := parseSemver(a)
+	vb, okB := parseSemver(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	if c := compareNumber(va.major, vb.major); c != 0 {
+		return c
+	}
+	if c := compareNumber(va.minor, vb.minor); c != 0 {
+		return c
+	}
+	if c := compareNumber(va.patch, vb.patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(va.prerelease, vb.prerelease)
+}
+
+// IsPrerelease reports whether a canonical version has a pre-release suffix.
+// Pseudo-versions count as pre-releases.
+func IsPrerelease(version string) bool {
+	v, ok := parseSemver(version)
+	return ok && v.prerelease != ""
+}
+
+// Major returns the major version prefix of a canonical version, such as "v1".
+func Major(version string) string {
+	v, ok := parseSemver(version)
+	if !ok {
+		return ""
+	}
+	return "v" + v.major
+}
+
+func compareNumber(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func comparePrerelease(a, b string) int {
+	// A version without pre-release has higher precedence
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	pa := strings.Split(a[1:], ".")
+	pb := strings.Split(b[1:], ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] == pb[i] {
+			continue
+		}
+		na, nb := isNumber(pa[i]), isNumber(pb[i])
+		switch {
+		case na && nb:
+			return compareNumber(pa[i], pb[i])
+		case na:
+			return -1
+		case nb:
+			return 1
+		default:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return 0
+}
+
+// PathMajor returns the major version suffix of a module path, such as
+// "v2" for example.com/m/v2 or "v3" for gopkg.in/yaml.v3, or "" for paths
+// without one.
+func PathMajor(modPath string) string {
+	if strings.HasPrefix(modPath, "gopkg.in/") {
+		if i := strings.LastIndex(modPath, ".v"); i >= 0 && isNumber(modPath[i+2:]) {
+			return modPath[i+1:]
+		}
+		return ""
+	}
+	if i := strings.LastIndex(modPath, "/v"); i >= 0 && isNumber(modPath[i+2:]) && modPath[i+2:] != "0" && modPath[i+2:] != "1" {
+		return modPath[i+1:]
+	}
+	return ""
+}
+
+// CheckPathMajor reports whether a canonical version may be a version of
+// modPath: a path ending in /vN (or .vN for gopkg.in) only has vN versions,
+// and other paths only have v0 and v1 versions or +incompatible ones.
+func CheckPathMajor(modPath, version string) error {
+	major := Major(version)
+	if major == "" {
+		return fmt.Errorf("invalid version %q", version)
+	}
+	suffix := PathMajor(modPath)
+	if strings.HasPrefix(modPath, "gopkg.in/") {
+		if suffix != major {
+			return fmt.Errorf("version %s does not match gopkg.in path %s", version, modPath)
+		}
+		return nil
+	}
+	switch {
+	case suffix != "" && (suffix != major || strings.HasSuffix(version, "+incompatible")):
+		return fmt.Errorf("version %s does not match major version suffix of %s", version, modPath)
+	case suffix == "" && major != "v0" && major != "v1" && !strings.HasSuffix(version, "+incompatible"):
+		return fmt.Errorf("version %s needs a /%s suffix on module path %s or +incompatible", version, major, modPath)
+	}
+	return nil
+}
+
+// PseudoVersion returns the pseudo-version of a commit made at t with
+// revision rev. The base is the latest tagged version the commit descends
+// from, or "" for none, in which case the pseudo-version is for the major
+// version major ("" for v0).
+func PseudoVersion(major, base string, t time.Time, rev string) string {
+	if len(rev) > 12 {
+		rev = rev[:12]
+	}
+	segment := t.UTC().Format("20060102150405") + "-" + rev
+	if base == "" {
+		if major == "" {
+			major = "v0"
+		}
+		return major + ".0.0-" + segment
+	}
+	base, build, _ := strings.Cut(base, "+")
+	if build != "" {
+		build = "+" + build
+	}
+	if IsPrerelease(base) {
+		return base + ".0." + segment + build
+	}
+	v, _ := parseSemver(base)
+	return "v" + v.major + "." + v.minor + "." + incDecimal(v.patch) + "-0." + segment + build
+}
+
+// incDecimal increments a decimal number of any length.
+func incDecimal(n string) string {
+	digits := []byte(n)
+	i := len(digits) - 1
+	for ; i >= 0 && digits[i] == '9'; i-- {
+		digits[i] = '0'
+	}
+	if i < 0 {
+		return "1" + string(digits)
+	}
+	digits[i]++
+	return string(digits)
+}
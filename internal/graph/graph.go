@@ -0,0 +1,183 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// MainNode is the node the roots hang off in formats that need a single
+// origin, such as go mod graph output. It stands for the modules.txt file.
+const MainNode = "modules.txt"
+
+// Graph is the module requirement graph discovered by a resolution run.
+// Nodes are "path@version" strings.
+type Graph struct {
+	Roots []string            `json:"roots"` // modules listed in modules.txt, as resolved
+	Edges map[string][]string `json:"edges"` // module -> its direct requirements
+}
+
+func New() *Graph {
+	return &Graph{Edges: make(map[string][]string)}
+}
+
+// AddRoot records node as one of the modules requested in modules.txt.
+func (g *Graph) AddRoot(node string) {
+	for _, r := range g.Roots {
+		if r == node {
+			return
+		}
+	}
+	g.Roots = append(g.Roots, node)
+}
+
+// AddEdge records that from requires to.
+func (g *Graph) AddEdge(from, to string) {
+	for _, c := range g.Edges[from] {
+		if c == to {
+			return
+		}
+	}
+	g.Edges[from] = append(g.Edges[from], to)
+}
+
+// Children returns the direct requirements of node.
+func (g *Graph) Children(node string) []string {
+	return g.Edges[node]
+}
+
+// Nodes returns every node in the graph, sorted.
+func (g *Graph) Nodes() []string {
+	seen := make(map[string]bool)
+	for _, r := range g.Roots {
+		seen[r] = true
+	}
+	for from, tos := range g.Edges {
+		seen[from] = true
+		for _, to := range tos {
+			seen[to] = true
+		}
+	}
+	nodes := make([]string, 0, len(seen))
+	for n := range seen {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// sortedEdges returns the edges in a stable order for output.
+func (g *Graph) sortedEdges() [][2]string {
+	var edges [][2]string
+	for from, tos := range g.Edges {
+		for _, to := range tos {
+			edges = append(edges, [2]string{from, to})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+func (g *Graph) sortedRoots() []string {
+	roots := append([]string(nil), g.Roots...)
+	sort.Strings(roots)
+	return roots
+}
+
+// Load reads a graph saved with Save.
+func Load(path string) (*Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g := New()
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, fmt.Errorf("failed to parse graph %s: %w", path, err)
+	}
+	if g.Edges == nil {
+		g.Edges = make(map[string][]string)
+	}
+	return g, nil
+}
+
+// Save writes the graph as JSON to path.
+func (g *Graph) Save(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Formats lists the names accepted by Write.
+var Formats = []string{"dot", "json", "modgraph"}
+
+// Write renders the graph in the named format.
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "dot":
+		return g.WriteDOT(w)
+	case "json":
+		return g.WriteJSON(w)
+	case "modgraph":
+		return g.WriteModGraph(w)
+	default:
+		return fmt.Errorf("unknown graph format %q (want one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// WriteDOT renders the graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph modules {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box];\n")
+	fmt.Fprintf(&b, "\t%q [shape=folder];\n", MainNode)
+	for _, r := range g.sortedRoots() {
+		fmt.Fprintf(&b, "\t%q -> %q;\n", MainNode, r)
+	}
+	for _, e := range g.sortedEdges() {
+		fmt.Fprintf(&b, "\t%q -> %q;\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON renders the graph as JSON with a stable edge order.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	out := struct {
+		Roots []string    `json:"roots"`
+		Nodes []string    `json:"nodes"`
+		Edges [][2]string `json:"edges"`
+	}{
+		Roots: g.sortedRoots(),
+		Nodes: g.Nodes(),
+		Edges: g.sortedEdges(),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// WriteModGraph renders the graph like go mod graph: one "from to" pair per
+// line, with MainNode standing in for the main module.
+func (g *Graph) WriteModGraph(w io.Writer) error {
+	var b strings.Builder
+	for _, r := range g.sortedRoots() {
+		fmt.Fprintf(&b, "%s %s\n", MainNode, r)
+	}
+	for _, e := range g.sortedEdges() {
+		fmt.Fprintf(&b, "%s %s\n", e[0], e[1])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testGraph() *Graph {
+	g := New()
+	g.AddRoot("example.com/a@v1.0.0")
+	g.AddEdge("example.com/a@v1.0.0", "example.com/c@v1.0.0")
+	g.AddEdge("example.com/a@v1.0.0", "example.com/b@v1.0.0")
+	g.AddEdge("example.com/a@v1.0.0", "example.com/b@v1.0.0")
+	g.AddEdge("example.com/c@v1.0.0", "example.com/b@v1.1.0")
+	return g
+}
+
+func TestWriteModGraph(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteModGraph(&buf); err != nil {
+		t.Fatalf("WriteModGraph failed: %v", err)
+	}
+
+	want := `modules.txt example.com/a@v1.0.0
+example.com/a@v1.0.0 example.com/b@v1.0.0
+example.com/a@v1.0.0 example.com/c@v1.0.0
+example.com/c@v1.0.0 example.com/b@v1.1.0
+`
+	if buf.String() != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().Write(&buf, "dot"); err != nil {
+		t.Fatalf("Write dot failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph modules {") {
+		t.Errorf("Missing digraph header:\n%s", out)
+	}
+	if !strings.Contains(out, `"example.com/c@v1.0.0" -> "example.com/b@v1.1.0";`) {
+		t.Errorf("Missing edge:\n%s", out)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	if err := testGraph().Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	g, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(g.Nodes()) != 4 {
+		t.Errorf("Got %d nodes, want 4: %v", len(g.Nodes()), g.Nodes())
+	}
+	if err := g.Write(&bytes.Buffer{}, "svg"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestShortestChains(t *testing.T) {
+	g := testGraph()
+	g.AddRoot("example.com/d@v1.0.0")
+	g.AddEdge("example.com/d@v1.0.0", "example.com/c@v1.0.0")
+
+	chains := g.ShortestChains("example.com/b@v1.1.0", 0)
+	if len(chains) != 2 {
+		t.Fatalf("Got %d chains, want 2: %v", len(chains), chains)
+	}
+	for _, chain := range chains {
+		if len(chain) != 3 || chain[1] != "example.com/c@v1.0.0" || chain[2] != "example.com/b@v1.1.0" {
+			t.Errorf("Unexpected chain: %v", chain)
+		}
+	}
+
+	if chains := g.ShortestChains("example.com/b@v1.1.0", 1); len(chains) != 1 {
+		t.Errorf("Limit not applied: got %d chains", len(chains))
+	}
+	if chains := g.ShortestChains("example.com/a@v1.0.0", 0); len(chains) != 1 || len(chains[0]) != 1 {
+		t.Errorf("Root should be its own chain: %v", chains)
+	}
+	if chains := g.ShortestChains("example.com/zzz@v1.0.0", 0); chains != nil {
+		t.Errorf("Unknown node should have no chains: %v", chains)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	g := testGraph()
+	if got := g.Match("example.com/b"); len(got) != 2 {
+		t.Errorf("Match by path got %v", got)
+	}
+	if got := g.Match("example.com/b@v1.1.0"); len(got) != 1 {
+		t.Errorf("Match by path@version got %v", got)
+	}
+	if got := g.Match("example.com/bb"); len(got) != 0 {
+		t.Errorf("Match should not match path prefixes: %v", got)
+	}
+}
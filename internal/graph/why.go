@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match returns the nodes that target refers to: the node itself for
+// "path@version", or every version of the module for a bare path.
+func (g *Graph) Match(target string) []string {
+	var matches []string
+	for _, n := range g.Nodes() {
+		if n == target || strings.HasPrefix(n, target+"@") && !strings.Contains(target, "@") {
+			matches = append(matches, n)
+		}
+	}
+	return matches
+}
+
+// ShortestChains returns the shortest requirement chains from any root to
+// node, each starting at a root and ending at node. At most limit chains are
+// returned; limit <= 0 means no limit. The result is nil if node is not
+// reachable from any root.
+func (g *Graph) ShortestChains(node string, limit int) [][]string {
+	// Breadth-first search from all roots at once, remembering every
+	// predecessor that reaches a node at its minimal depth
+	depth := make(map[string]int)
+	parents := make(map[string][]string)
+	var queue []string
+	for _, r := range g.sortedRoots() {
+		depth[r] = 0
+		queue = append(queue, r)
+	}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == node {
+			continue
+		}
+		children := append([]string(nil), g.Edges[cur]...)
+		sort.Strings(children)
+		for _, child := range children {
+			d, seen := depth[child]
+			if !seen {
+				depth[child] = depth[cur] + 1
+				parents[child] = []string{cur}
+				queue = append(queue, child)
+			} else if d == depth[cur]+1 {
+				parents[child] = append(parents[child], cur)
+			}
+		}
+	}
+
+	if _, ok := depth[node]; !ok {
+		return nil
+	}
+
+	var chains [][]string
+	var walk func(n string, suffix []string)
+	walk = func(n string, suffix []string) {
+		if limit > 0 && len(chains) >= limit {
+			return
+		}
+		suffix = append([]string{n}, suffix...)
+		if depth[n] == 0 {
+			chains = append(chains, suffix)
+			return
+		}
+		for _, p := range parents[n] {
+			walk(p, suffix)
+		}
+	}
+	walk(node, nil)
+	return chains
+}
@@ -0,0 +1,79 @@
+package license
+
+import (
+	"regexp"
+	"strings"
+)
+
+// signature identifies a license by phrases that must all occur in its
+// normalized text. Signatures are tried in order, so licenses whose text
+// contains another license's phrases (LGPL contains "general public
+// license", BSD-3-Clause contains the BSD-2-Clause conditions) come first.
+type signature struct {
+	id      string
+	phrases []string
+}
+
+var signatures = []signature{
+	{"AGPL-3.0", []string{"gnu affero general public license"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"LGPL-2.0", []string{"gnu library general public license"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"EPL-1.0", []string{"eclipse public license", "1.0"}},
+	{"BSL-1.0", []string{"boost software license"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "names of its contributors may be used"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge, to any person obtaining a copy"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"ISC", []string{"permission to use, copy, modify, and distribute this software for any purpose"}},
+	{"Zlib", []string{"altered source versions must be plainly marked as such"}},
+}
+
+var (
+	spdxTagRE  = regexp.MustCompile(`(?i)spdx-license-identifier:\s*([A-Za-z0-9.+-]+)`)
+	spaceRE    = regexp.MustCompile(`\s+`)
+	commentRE  = regexp.MustCompile(`(?m)^\s*(//|#|\*|/\*|\*/)`)
+	quoteChars = strings.NewReplacer("‘", "'", "’", "'", "“", `"`, "”", `"`)
+)
+
+// Classify returns the SPDX identifier of the license in text, or "" if it is
+// not recognized. An explicit SPDX-License-Identifier tag wins over the text.
+func Classify(text string) string {
+	if m := spdxTagRE.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+
+	normalized := normalize(text)
+	for _, sig := range signatures {
+		if containsAll(normalized, sig.phrases) {
+			return sig.id
+		}
+	}
+	return ""
+}
+
+// normalize lower-cases text and collapses comment markers, quotes and
+// whitespace so that reflowed or commented license texts still match.
+func normalize(text string) string {
+	text = commentRE.ReplaceAllString(text, " ")
+	text = quoteChars.Replace(text)
+	text = spaceRE.ReplaceAllString(text, " ")
+	return strings.ToLower(text)
+}
+
+func containsAll(text string, phrases []string) bool {
+	for _, p := range phrases {
+		if !strings.Contains(text, p) {
+			return false
+		}
+	}
+	return true
+}
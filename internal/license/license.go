@@ -0,0 +1,131 @@
+package license
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Status values of a Report.
+const (
+	StatusDetected = "detected" // every license file was classified
+	StatusUnknown  = "unknown"  // at least one license file was not recognized
+	StatusMissing  = "missing"  // the module has no license file
+)
+
+// MetadataExt is the extension of the license metadata stored next to a
+// version's .zip in an @v directory.
+const MetadataExt = ".license.json"
+
+// maxLicenseSize bounds how much of a license file is read for classification.
+const maxLicenseSize = 1 << 20
+
+// File is one license file found at the root of a module zip.
+type File struct {
+	Name    string `json:"name"`
+	License string `json:"license,omitempty"` // SPDX identifier, empty if unrecognized
+}
+
+// Report is the license information of one module version.
+type Report struct {
+	Path       string    `json:"path"`
+	Version    string    `json:"version"`
+	Licenses   []string  `json:"licenses"`
+	Files      []File    `json:"files"`
+	Status     string    `json:"status"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// licenseFileRE matches the names of files that hold a module's license.
+var licenseFileRE = regexp.MustCompile(`(?i)^(licen[cs]e|copying|unlicense|copyright)([-._].*)?$`)
+
+// IsLicenseFile reports whether name looks like a license file.
+func IsLicenseFile(name string) bool {
+	return licenseFileRE.MatchString(name)
+}
+
+// DetectZip classifies the license files at the root of a module zip.
+func DetectZip(zipPath, modPath, version string) (*Report, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", zipPath, err)
+	}
+	defer zr.Close()
+
+	report := &Report{
+		Path:       modPath,
+		Version:    version,
+		Licenses:   []string{},
+		Files:      []File{},
+		DetectedAt: time.Now().UTC(),
+	}
+
+	prefix := modPath + "@" + version + "/"
+	seen := make(map[string]bool)
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, prefix)
+		if name == f.Name || strings.Contains(name, "/") || !IsLicenseFile(path.Base(name)) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxLicenseSize))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+
+		id := Classify(string(data))
+		report.Files = append(report.Files, File{Name: name, License: id})
+		if id != "" && !seen[id] {
+			seen[id] = true
+			report.Licenses = append(report.Licenses, id)
+		}
+	}
+
+	sort.Strings(report.Licenses)
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Name < report.Files[j].Name })
+
+	report.Status = StatusDetected
+	if len(report.Files) == 0 {
+		report.Status = StatusMissing
+	}
+	for _, f := range report.Files {
+		if f.License == "" {
+			report.Status = StatusUnknown
+		}
+	}
+	return report, nil
+}
+
+// WriteMetadata stores report as JSON in file.
+func WriteMetadata(file string, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// ReadMetadata loads a report stored with WriteMetadata.
+func ReadMetadata(file string) (*Report, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return &report, nil
+}
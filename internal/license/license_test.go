@@ -0,0 +1,123 @@
+package license
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const mitText = `MIT License
+
+Copyright (c) 2023 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction.`
+
+const bsd3Text = `Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+   * Neither the name of Google Inc. nor the names of its
+contributors may be used to endorse or promote products derived from
+this software without specific prior written permission.`
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"mit", mitText, "MIT"},
+		{"bsd-3", bsd3Text, "BSD-3-Clause"},
+		{"apache", "Apache License\n   Version 2.0, January 2004\n   http://www.apache.org/licenses/", "Apache-2.0"},
+		{"lgpl is not gpl", "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", "LGPL-3.0"},
+		{"gpl-2", "GNU GENERAL PUBLIC LICENSE\nVersion 2, June 1991", "GPL-2.0"},
+		{"spdx tag", "// SPDX-License-Identifier: MPL-2.0\n", "MPL-2.0"},
+		{"unknown", "All rights reserved. Do not copy.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.text); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	zipPath := filepath.Join(t.TempDir(), "module.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return zipPath
+}
+
+func TestDetectZip(t *testing.T) {
+	const prefix = "example.com/m@v1.0.0/"
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		licenses []string
+		status   string
+	}{
+		{
+			name: "dual licensed",
+			files: map[string]string{
+				prefix + "LICENSE-MIT":           mitText,
+				prefix + "LICENSE.apache":        "Apache License Version 2.0",
+				prefix + "go.mod":                "module example.com/m\n",
+				prefix + "third_party/x/LICENSE": "GNU GENERAL PUBLIC LICENSE Version 3",
+			},
+			licenses: []string{"Apache-2.0", "MIT"},
+			status:   StatusDetected,
+		},
+		{
+			name:     "unrecognized license",
+			files:    map[string]string{prefix + "COPYING": "All rights reserved."},
+			licenses: []string{},
+			status:   StatusUnknown,
+		},
+		{
+			name:     "no license file",
+			files:    map[string]string{prefix + "go.mod": "module example.com/m\n"},
+			licenses: []string{},
+			status:   StatusMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := DetectZip(writeZip(t, tt.files), "example.com/m", "v1.0.0")
+			if err != nil {
+				t.Fatalf("DetectZip failed: %v", err)
+			}
+			if report.Status != tt.status {
+				t.Errorf("Status = %s, want %s", report.Status, tt.status)
+			}
+			if len(report.Licenses) != len(tt.licenses) {
+				t.Fatalf("Licenses = %v, want %v", report.Licenses, tt.licenses)
+			}
+			for i := range tt.licenses {
+				if report.Licenses[i] != tt.licenses[i] {
+					t.Errorf("Licenses = %v, want %v", report.Licenses, tt.licenses)
+				}
+			}
+		})
+	}
+}
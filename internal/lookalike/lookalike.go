@@ -0,0 +1,168 @@
+// Package lookalike flags module paths that imitate well-known or already
+// mirrored module paths: small typos, homoglyphs, swapped owner and
+// repository names, and case variations.
+package lookalike
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Reason values of a Finding.
+const (
+	ReasonCase      = "case"      // differs only in letter case
+	ReasonHomoglyph = "homoglyph" // differs only in look-alike characters
+	ReasonSwapped   = "swapped"   // owner and repository names swapped
+	ReasonTypo      = "typo"      // within a small edit distance
+)
+
+// Finding is a module path that resembles a known path.
+type Finding struct {
+	Path     string   `json:"path"`
+	Versions []string `json:"versions,omitempty"`
+	Similar  string   `json:"similar_to"`
+	Reason   string   `json:"reason"`
+	Distance int      `json:"distance,omitempty"` // edit distance for ReasonTypo
+	Root     string   `json:"root,omitempty"`     // modules.txt entry that pulled the path in
+	Chain    []string `json:"chain,omitempty"`    // requirement chain from Root
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("%s resembles %s (%s", f.Path, f.Similar, f.Reason)
+	if f.Reason == ReasonTypo {
+		s += fmt.Sprintf(", distance %d", f.Distance)
+	}
+	return s + ")"
+}
+
+// Checker compares module paths against a set of known paths.
+type Checker struct {
+	known map[string]bool
+	paths []string
+}
+
+// NewChecker returns a checker for the given known paths, in addition to
+// the Popular ones.
+func NewChecker(known []string) *Checker {
+	c := &Checker{
+		known: make(map[string]bool),
+	}
+	for _, list := range [][]string{Popular, known} {
+		for _, p := range list {
+			p = stripMajor(p)
+			if c.known[p] {
+				continue
+			}
+			c.known[p] = true
+			c.paths = append(c.paths, p)
+		}
+	}
+	return c
+}
+
+// Check returns the known paths that modPath resembles. A path that is
+// itself known, or that belongs to the same owner as a known path, is never
+// flagged: only a different owner can squat a name.
+func (c *Checker) Check(modPath string) []Finding {
+	base := stripMajor(modPath)
+	if c.known[base] {
+		return nil
+	}
+
+	var findings []Finding
+	for _, known := range c.paths {
+		if owner(known) == owner(base) {
+			continue
+		}
+		f := Finding{Path: modPath, Similar: known}
+		switch {
+		case strings.EqualFold(base, known):
+			f.Reason = ReasonCase
+		case skeleton(base) == skeleton(known):
+			f.Reason = ReasonHomoglyph
+		case swapped(base, known):
+			f.Reason = ReasonSwapped
+		default:
+			max := maxDistance(known)
+			d := distance(strings.ToLower(base), strings.ToLower(known), max)
+			if d > max {
+				continue
+			}
+			f.Reason = ReasonTypo
+			f.Distance = d
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// maxDistance is the edit distance within which a path counts as a typo of
+// known. Short paths allow a single edit to keep false positives down.
+func maxDistance(known string) int {
+	if len(known) < 20 {
+		return 1
+	}
+	return 2
+}
+
+// owner returns the part of a module path that identifies who controls it:
+// host/org for code hosting sites, the host otherwise.
+func owner(modPath string) string {
+	parts := strings.SplitN(modPath, "/", 3)
+	if len(parts) >= 2 && hostsWithOwners[strings.ToLower(parts[0])] {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+var hostsWithOwners = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+	"gitee.com":     true,
+	"codeberg.org":  true,
+}
+
+// swapped reports whether a is host/repo/org for a known host/org/repo.
+func swapped(a, known string) bool {
+	pa := strings.Split(a, "/")
+	pk := strings.Split(known, "/")
+	if len(pa) != 3 || len(pk) != 3 || !hostsWithOwners[strings.ToLower(pk[0])] {
+		return false
+	}
+	return strings.EqualFold(pa[0], pk[0]) && strings.EqualFold(pa[1], pk[2]) && strings.EqualFold(pa[2], pk[1])
+}
+
+// stripMajor removes a /vN or gopkg.in .vN major version suffix, so that the
+// major versions of a module are compared as one path.
+func stripMajor(modPath string) string {
+	if i := strings.LastIndex(modPath, "/v"); i > 0 && isDigits(modPath[i+2:]) {
+		return modPath[:i]
+	}
+	if strings.HasPrefix(modPath, "gopkg.in/") {
+		if i := strings.LastIndex(modPath, ".v"); i > 0 && isDigits(modPath[i+2:]) {
+			return modPath[:i]
+		}
+	}
+	return modPath
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// WriteReport writes findings as JSON to file.
+func WriteReport(file string, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Lookalikes []Finding `json:"lookalikes"`
+	}{findings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
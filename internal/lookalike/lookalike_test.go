@@ -0,0 +1,62 @@
+package lookalike
+
+import "testing"
+
+func TestChecker(t *testing.T) {
+	c := NewChecker([]string{"corp.example.com/platform/auth"})
+
+	tests := []struct {
+		path    string
+		similar string
+		reason  string
+	}{
+		{"github.com/Sirupsen/logrus", "github.com/sirupsen/logrus", ReasonCase},
+		{"github.com/slrupsen/logrus", "github.com/sirupsen/logrus", ReasonHomoglyph},
+		{"github.com/spfl3/cobra", "github.com/spf13/cobra", ReasonHomoglyph},
+		{"github.com/cobra/spf13", "github.com/spf13/cobra", ReasonSwapped},
+		{"github.com/stretchr/testfy", "", ""}, // same owner
+		{"github.com/strechr/testify", "github.com/stretchr/testify", ReasonTypo},
+		{"github.com/gorila/mux/v2", "github.com/gorilla/mux", ReasonTypo},
+		{"corp.example.corn/platform/auth", "corp.example.com/platform/auth", ReasonHomoglyph},
+		{"golang.org/x/net", "", ""},
+		{"github.com/spf13/cobra/v2", "", ""},
+		{"golang.org/x/nett", "", ""}, // same owner
+		{"github.com/example/unrelated", "", ""},
+	}
+	for _, tt := range tests {
+		findings := c.Check(tt.path)
+		if tt.similar == "" {
+			if len(findings) != 0 {
+				t.Errorf("Check(%q) = %v, want nothing", tt.path, findings)
+			}
+			continue
+		}
+		found := false
+		for _, f := range findings {
+			if f.Similar == tt.similar && f.Reason == tt.reason {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Check(%q) = %v, want %s of %s", tt.path, findings, tt.reason, tt.similar)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"cobra", "cobra", 0},
+		{"cobra", "corba", 1}, // transposition
+		{"cobra", "cobras", 1},
+		{"cobra", "kobre", 2},
+		{"cobra", "viper", 3}, // capped at max+1
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b, 2); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
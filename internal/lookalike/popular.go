@@ -0,0 +1,93 @@
+package lookalike
+
+// Popular are widely used module paths that attackers are known to imitate.
+// Paths already in the storage root are added to these at run time.
+var Popular = []string{
+	"cloud.google.com/go",
+	"github.com/aws/aws-sdk-go",
+	"github.com/aws/aws-sdk-go-v2",
+	"github.com/beorn7/perks",
+	"github.com/boltdb/bolt",
+	"github.com/cespare/xxhash",
+	"github.com/davecgh/go-spew",
+	"github.com/dgrijalva/jwt-go",
+	"github.com/docker/docker",
+	"github.com/fatih/color",
+	"github.com/fsnotify/fsnotify",
+	"github.com/gin-gonic/gin",
+	"github.com/go-chi/chi",
+	"github.com/go-kit/kit",
+	"github.com/go-logr/logr",
+	"github.com/go-redis/redis",
+	"github.com/go-sql-driver/mysql",
+	"github.com/go-playground/validator",
+	"github.com/gofiber/fiber",
+	"github.com/gogo/protobuf",
+	"github.com/golang-jwt/jwt",
+	"github.com/golang/glog",
+	"github.com/golang/mock",
+	"github.com/golang/protobuf",
+	"github.com/google/go-cmp",
+	"github.com/google/uuid",
+	"github.com/gorilla/mux",
+	"github.com/gorilla/websocket",
+	"github.com/grpc-ecosystem/grpc-gateway",
+	"github.com/hashicorp/consul",
+	"github.com/hashicorp/go-multierror",
+	"github.com/hashicorp/hcl",
+	"github.com/hashicorp/vault",
+	"github.com/jackc/pgx",
+	"github.com/jmoiron/sqlx",
+	"github.com/json-iterator/go",
+	"github.com/labstack/echo",
+	"github.com/lib/pq",
+	"github.com/mattn/go-isatty",
+	"github.com/mattn/go-sqlite3",
+	"github.com/mitchellh/mapstructure",
+	"github.com/onsi/ginkgo",
+	"github.com/onsi/gomega",
+	"github.com/pkg/errors",
+	"github.com/pmezard/go-difflib",
+	"github.com/prometheus/client_golang",
+	"github.com/prometheus/common",
+	"github.com/redis/go-redis",
+	"github.com/rs/zerolog",
+	"github.com/sirupsen/logrus",
+	"github.com/spf13/afero",
+	"github.com/spf13/cast",
+	"github.com/spf13/cobra",
+	"github.com/spf13/pflag",
+	"github.com/spf13/viper",
+	"github.com/stretchr/objx",
+	"github.com/stretchr/testify",
+	"github.com/urfave/cli",
+	"github.com/valyala/fasthttp",
+	"go.etcd.io/bbolt",
+	"go.etcd.io/etcd",
+	"go.mongodb.org/mongo-driver",
+	"go.opentelemetry.io/otel",
+	"go.uber.org/atomic",
+	"go.uber.org/multierr",
+	"go.uber.org/zap",
+	"golang.org/x/crypto",
+	"golang.org/x/exp",
+	"golang.org/x/mod",
+	"golang.org/x/net",
+	"golang.org/x/oauth2",
+	"golang.org/x/sync",
+	"golang.org/x/sys",
+	"golang.org/x/term",
+	"golang.org/x/text",
+	"golang.org/x/time",
+	"golang.org/x/tools",
+	"google.golang.org/api",
+	"google.golang.org/genproto",
+	"google.golang.org/grpc",
+	"google.golang.org/protobuf",
+	"gopkg.in/yaml",
+	"gorm.io/gorm",
+	"k8s.io/api",
+	"k8s.io/apimachinery",
+	"k8s.io/client-go",
+	"sigs.k8s.io/yaml",
+}
@@ -0,0 +1,85 @@
+package lookalike
+
+import "strings"
+
+// confusables maps characters to the character they are commonly mistaken
+// for. Module paths are ASCII, but proxies and VCS hosts are not the only
+// source of module paths, so common Cyrillic and Greek lookalikes are
+// included as well.
+var confusables = map[rune]string{
+	'0': "o", '1': "l", 'i': "l", 'I': "l", '|': "l",
+	'5': "s", '_': "-",
+	'а': "a", 'е': "e", 'о': "o", 'р': "p", 'с': "c", 'х': "x", 'у': "y",
+	'і': "l", 'ј': "j", 'ѕ': "s", 'ԁ': "d", 'ɡ': "g",
+	'α': "a", 'ο': "o", 'ν': "v", 'ρ': "p", 'τ': "t", 'ι': "l",
+}
+
+// sequences are multi-character lookalikes, replaced after confusables.
+var sequences = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d")
+
+// skeleton maps a path to a canonical form in which look-alike characters
+// are identical. Letter case is preserved, so case confusions are told
+// apart from homoglyphs.
+func skeleton(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if c, ok := confusables[r]; ok {
+			b.WriteString(c)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return sequences.Replace(b.String())
+}
+
+// distance returns the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and transpositions of adjacent
+// characters. Once the distance is known to exceed max, max+1 is returned.
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && prev2[j-2]+1 < cur[j] {
+				cur[j] = prev2[j-2] + 1
+			}
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	if prev[len(rb)] > max {
+		return max + 1
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "Requests.", "endpoint", "code")
+	c.Inc("zip", "200")
+	c.Inc("zip", "200")
+	c.Add(3, "info", "404")
+	r.Gauge("up", "Up.").Set(1)
+	r.GaugeFunc("size_bytes", "Size.", func() float64 { return 1.5e9 })
+	h := r.Histogram("duration_seconds", "Latency.", []float64{0.1, 1}, "endpoint")
+	h.Observe(0.05, "zip")
+	h.Observe(0.5, "zip")
+	h.Observe(2, "zip")
+	r.Counter("escaped_total", "Escaping.", "v").Inc("a\"b\\c\nd")
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{endpoint="info",code="404"} 3
+requests_total{endpoint="zip",code="200"} 2
+# HELP up Up.
+# TYPE up gauge
+up 1
+# HELP size_bytes Size.
+# TYPE size_bytes gauge
+size_bytes 1.5e+09
+# HELP duration_seconds Latency.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{endpoint="zip",le="0.1"} 1
+duration_seconds_bucket{endpoint="zip",le="1"} 2
+duration_seconds_bucket{endpoint="zip",le="+Inf"} 3
+duration_seconds_sum{endpoint="zip"} 2.55
+duration_seconds_count{endpoint="zip"} 3
+# HELP escaped_total Escaping.
+# TYPE escaped_total counter
+escaped_total{v="a\"b\\c\nd"} 1
+`
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestRegistry_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prefill.prom")
+	r := NewRegistry()
+	r.Gauge("packed", "Packed.").Set(7)
+	if err := r.WriteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "packed 7\n") {
+		t.Errorf("file = %q", data)
+	}
+	// The temporary file is renamed into place
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files in directory, want 1", len(entries))
+	}
+}
@@ -0,0 +1,396 @@
+// Package moddoc reads the files and package documentation of a module
+// version straight from its zip, without extracting it.
+package moddoc
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxFileSize bounds how much of a file is read for viewing or parsing.
+const MaxFileSize = 4 << 20
+
+// ErrTooLarge is returned for files larger than MaxFileSize.
+var ErrTooLarge = errors.New("file too large")
+
+// File is a file in a module zip, with its path relative to the module root.
+type File struct {
+	Name string
+	Size int64
+}
+
+// Entry is a file or directory directly inside a directory of the module.
+type Entry struct {
+	Name  string
+	IsDir bool
+	Size  int64 // files only
+}
+
+// Package is a Go package in a module.
+type Package struct {
+	ImportPath string
+	Dir        string // relative to the module root, "" for the root
+	Name       string
+	Synopsis   string
+}
+
+// Module is an open module zip.
+type Module struct {
+	Path    string
+	Version string
+
+	zr    *zip.ReadCloser
+	files map[string]*zip.File // by name relative to the module root
+	names []string             // sorted
+}
+
+// Open opens the zip of modPath@version.
+func Open(zipPath, modPath, version string) (*Module, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", zipPath, err)
+	}
+	m := &Module{
+		Path:    modPath,
+		Version: version,
+		zr:      zr,
+		files:   make(map[string]*zip.File),
+	}
+	prefix := modPath + "@" + version + "/"
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		m.files[name] = f
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+	return m, nil
+}
+
+// Close closes the zip.
+func (m *Module) Close() error {
+	return m.zr.Close()
+}
+
+// Files returns every file in the module, sorted by name.
+func (m *Module) Files() []File {
+	files := make([]File, 0, len(m.names))
+	for _, name := range m.names {
+		files = append(files, File{Name: name, Size: int64(m.files[name].UncompressedSize64)})
+	}
+	return files
+}
+
+// Stat reports whether name is a file or a directory of the module. The
+// module root is the directory "".
+func (m *Module) Stat(name string) (isDir, ok bool) {
+	if _, ok := m.files[name]; ok {
+		return false, true
+	}
+	if name == "" {
+		return true, true
+	}
+	prefix := name + "/"
+	i := sort.SearchStrings(m.names, prefix)
+	return true, i < len(m.names) && strings.HasPrefix(m.names[i], prefix)
+}
+
+// ReadDir returns the entries of a directory, directories first.
+func (m *Module) ReadDir(dir string) []Entry {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+	var entries []Entry
+	seen := make(map[string]bool)
+	for _, name := range m.names {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if sub, _, isDir := strings.Cut(rest, "/"); isDir {
+			if !seen[sub] {
+				seen[sub] = true
+				entries = append(entries, Entry{Name: sub, IsDir: true})
+			}
+			continue
+		}
+		entries = append(entries, Entry{Name: rest, Size: int64(m.files[name].UncompressedSize64)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].IsDir && !entries[j].IsDir })
+	return entries
+}
+
+// ReadFile returns the contents of a file.
+func (m *Module) ReadFile(name string) ([]byte, error) {
+	f, ok := m.files[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	if f.UncompressedSize64 > MaxFileSize {
+		return nil, ErrTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// IsText reports whether data looks like text that can be shown as is.
+func IsText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// Packages returns the packages of the module, sorted by import path.
+// Directories named testdata or starting with "." or "_" are ignored, like
+// the go command does.
+func (m *Module) Packages() []Package {
+	dirs := make(map[string][]string)
+	for _, name := range m.names {
+		dir, file := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+		if isGoFile(file) && !strings.HasSuffix(file, "_test.go") && !ignoredDir(dir) {
+			dirs[dir] = append(dirs[dir], name)
+		}
+	}
+
+	var pkgs []Package
+	for dir, names := range dirs {
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for _, name := range names {
+			if f := m.parse(fset, name, parser.PackageClauseOnly|parser.ParseComments); f != nil {
+				files = append(files, f)
+			}
+		}
+		files = samePackage(files)
+		if len(files) == 0 {
+			continue
+		}
+		pkg := Package{ImportPath: m.importPath(dir), Dir: dir, Name: files[0].Name.Name}
+		for _, f := range files {
+			if f.Doc != nil {
+				pkg.Synopsis = (&doc.Package{}).Synopsis(f.Doc.Text())
+				break
+			}
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ImportPath < pkgs[j].ImportPath })
+	return pkgs
+}
+
+// Doc is the documentation of a package.
+type Doc struct {
+	*doc.Package
+	Fset *token.FileSet
+
+	comments []*ast.CommentGroup // of every file, for printing declarations
+}
+
+// Doc parses the package in dir, including the examples of its test files.
+// Only exported identifiers are documented.
+func (m *Module) Doc(dir string) (*Doc, error) {
+	if ignoredDir(dir) {
+		return nil, fs.ErrNotExist
+	}
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+	fset := token.NewFileSet()
+	var files, tests []*ast.File
+	for _, name := range m.names {
+		file, ok := strings.CutPrefix(name, prefix)
+		if !ok || strings.Contains(file, "/") || !isGoFile(file) {
+			continue
+		}
+		f := m.parse(fset, name, parser.ParseComments)
+		if f == nil {
+			continue
+		}
+		if strings.HasSuffix(file, "_test.go") {
+			tests = append(tests, f)
+		} else {
+			files = append(files, f)
+		}
+	}
+	files = samePackage(files)
+	if len(files) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	d := &Doc{Fset: fset}
+	for _, f := range files {
+		d.comments = append(d.comments, f.Comments...)
+	}
+	// Only tests of the package itself or its external test package
+	// contribute examples
+	name := files[0].Name.Name
+	for _, f := range tests {
+		if f.Name.Name == name || f.Name.Name == name+"_test" {
+			files = append(files, f)
+		}
+	}
+	pkg, err := doc.NewFromFiles(fset, files, m.importPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	d.Package = pkg
+	return d, nil
+}
+
+// Decl formats a declaration with its comments. Function bodies are left
+// out.
+func (d *Doc) Decl(decl ast.Decl) string {
+	if fn, ok := decl.(*ast.FuncDecl); ok {
+		sig := *fn
+		sig.Body = nil
+		sig.Doc = nil
+		decl = &sig
+	} else if gen, ok := decl.(*ast.GenDecl); ok {
+		g := *gen
+		g.Doc = nil
+		decl = &g
+	}
+	return d.print(&printer.CommentedNode{Node: decl, Comments: d.comments})
+}
+
+// Example formats the code of an example, without the enclosing braces of a
+// function body.
+func (d *Doc) Example(ex *doc.Example) string {
+	code := d.print(&printer.CommentedNode{Node: ex.Code, Comments: ex.Comments})
+	if _, ok := ex.Code.(*ast.BlockStmt); !ok {
+		return code
+	}
+	code = strings.TrimSuffix(strings.TrimPrefix(code, "{"), "}")
+	code = strings.Trim(code, "\n")
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "\t")
+	}
+	code = strings.Join(lines, "\n")
+	// The expected output is shown separately
+	if loc := outputRE.FindStringIndex(code); loc != nil {
+		code = strings.TrimRight(code[:loc[0]], "\n")
+	}
+	return code
+}
+
+// outputRE matches the output comment that ends an example.
+var outputRE = regexp.MustCompile(`(?ims)^//\s*(unordered )?output:.*\z`)
+
+func (d *Doc) print(node interface{}) string {
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, d.Fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func (m *Module) importPath(dir string) string {
+	if dir == "" {
+		return m.Path
+	}
+	return m.Path + "/" + dir
+}
+
+// parse parses a Go file of the module, or returns nil if it cannot be read
+// or parsed, or is not built on linux/amd64. The file is decompressed once,
+// when the build constraints are checked, and the same bytes are parsed.
+func (m *Module) parse(fset *token.FileSet, name string, mode parser.Mode) *ast.File {
+	var data []byte
+	ctxt := buildContext(func(file string) (io.ReadCloser, error) {
+		var err error
+		if data, err = m.ReadFile(file); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+	// Files excluded by their name are never opened
+	if ok, err := ctxt.MatchFile("", name); err != nil || !ok || data == nil {
+		return nil
+	}
+	f, err := parser.ParseFile(fset, name, data, mode)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// buildContext returns the build context documentation is rendered for,
+// linux/amd64 like pkg.go.dev, reading files with open. It excludes files
+// for other platforms and those constrained by //go:build ignore.
+func buildContext(open func(name string) (io.ReadCloser, error)) *build.Context {
+	ctxt := build.Default
+	ctxt.GOOS = "linux"
+	ctxt.GOARCH = "amd64"
+	ctxt.CgoEnabled = true
+	ctxt.JoinPath = path.Join
+	ctxt.OpenFile = open
+	return &ctxt
+}
+
+// samePackage drops files whose package name differs from the most common
+// one, such as stray main packages next to a library.
+func samePackage(files []*ast.File) []*ast.File {
+	count := make(map[string]int)
+	best := ""
+	for _, f := range files {
+		name := f.Name.Name
+		count[name]++
+		if count[name] > count[best] || (count[name] == count[best] && name < best) {
+			best = name
+		}
+	}
+	var same []*ast.File
+	for _, f := range files {
+		if f.Name.Name == best {
+			same = append(same, f)
+		}
+	}
+	return same
+}
+
+func isGoFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "_")
+}
+
+// ignoredDir reports whether the go command ignores packages in dir.
+func ignoredDir(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "testdata" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package moddoc
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeZip(t *testing.T, prefix string, files map[string]string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "m.zip")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(prefix + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return file
+}
+
+const greetGo = `// Package greet says hello.
+package greet
+
+// Greeting is the default greeting.
+const Greeting = "hello"
+
+// Hello returns a greeting for name.
+func Hello(name string) string {
+	return Greeting + " " + name
+}
+
+// Greeter greets people.
+type Greeter struct {
+	// Name is who is greeted.
+	Name   string
+	prefix string
+}
+
+// Greet greets g's person.
+func (g *Greeter) Greet() string { return Hello(g.Name) }
+
+func internal() {}
+`
+
+func openTestModule(t *testing.T) *Module {
+	t.Helper()
+	file := writeZip(t, "example.com/greet@v1.0.0/", map[string]string{
+		"go.mod":           "module example.com/greet\n",
+		"README.md":        "# greet\n",
+		"greet.go":         greetGo,
+		"greet_windows.go": "package greet\n\n// Windows is only built on Windows.\nfunc Windows() {}\n",
+		"gen.go":           "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"example_test.go": `package greet_test
+
+import "example.com/greet"
+
+func ExampleHello() {
+	println(greet.Hello("gopher"))
+	// Output: hello gopher
+}
+`,
+		"loud/loud.go":    "// Package loud shouts.\npackage loud\n",
+		"testdata/x/x.go": "package x\n",
+		"assets/logo.png": "\x89PNG\x00\x01",
+	})
+	m, err := Open(file, "example.com/greet", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestModule_Files(t *testing.T) {
+	m := openTestModule(t)
+
+	var names []string
+	for _, e := range m.ReadDir("") {
+		names = append(names, e.Name)
+	}
+	want := []string{"assets", "loud", "testdata", "README.md", "example_test.go", "gen.go", "go.mod", "greet.go", "greet_windows.go"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir(\"\") = %v, want %v", names, want)
+	}
+	for name, want := range map[string][2]bool{
+		"":          {true, true},
+		"loud":      {true, true},
+		"lo":        {true, false},
+		"greet.go":  {false, true},
+		"missing.c": {true, false},
+	} {
+		isDir, ok := m.Stat(name)
+		if ok != want[1] || (ok && isDir != want[0]) {
+			t.Errorf("Stat(%q) = %v, %v, want %v, %v", name, isDir, ok, want[0], want[1])
+		}
+	}
+
+	data, err := m.ReadFile("README.md")
+	if err != nil || string(data) != "# greet\n" || !IsText(data) {
+		t.Errorf("ReadFile(README.md) = %q, %v", data, err)
+	}
+	if data, _ := m.ReadFile("assets/logo.png"); IsText(data) {
+		t.Error("PNG file reported as text")
+	}
+	if _, err := m.ReadFile("missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile(missing) error = %v, want not exist", err)
+	}
+}
+
+func TestModule_Packages(t *testing.T) {
+	m := openTestModule(t)
+	want := []Package{
+		{ImportPath: "example.com/greet", Dir: "", Name: "greet", Synopsis: "Package greet says hello."},
+		{ImportPath: "example.com/greet/loud", Dir: "loud", Name: "loud", Synopsis: "Package loud shouts."},
+	}
+	if got := m.Packages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Packages() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestModule_Doc(t *testing.T) {
+	m := openTestModule(t)
+	d, err := m.Doc("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "greet" || d.ImportPath != "example.com/greet" {
+		t.Errorf("package %s %s", d.Name, d.ImportPath)
+	}
+	// Windows-only and ignored files are left out
+	if len(d.Funcs) != 1 || d.Funcs[0].Name != "Hello" {
+		t.Fatalf("Funcs = %v", d.Funcs)
+	}
+	if got := d.Decl(d.Funcs[0].Decl); got != "func Hello(name string) string" {
+		t.Errorf("Decl(Hello) = %q", got)
+	}
+	if len(d.Types) != 1 || len(d.Types[0].Methods) != 1 {
+		t.Fatalf("Types = %v", d.Types)
+	}
+	decl := d.Decl(d.Types[0].Decl)
+	if !strings.Contains(decl, "// Name is who is greeted.") || strings.Contains(decl, "prefix") {
+		t.Errorf("Decl(Greeter) = %q", decl)
+	}
+
+	examples := d.Funcs[0].Examples
+	if len(examples) != 1 {
+		t.Fatalf("examples of Hello = %v", examples)
+	}
+	if got := d.Example(examples[0]); got != `println(greet.Hello("gopher"))` {
+		t.Errorf("Example = %q", got)
+	}
+	if examples[0].Output != "hello gopher\n" {
+		t.Errorf("Output = %q", examples[0].Output)
+	}
+
+	for _, dir := range []string{"testdata/x", "assets", "missing"} {
+		if _, err := m.Doc(dir); !os.IsNotExist(err) {
+			t.Errorf("Doc(%q) error = %v, want not exist", dir, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package modzip
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Size limits of golang.org/x/mod/zip.
+const (
+	MaxZipFile = 500 << 20 // total uncompressed size of a module zip
+	MaxGoMod   = 16 << 20  // size of go.mod
+	MaxLICENSE = 16 << 20  // size of LICENSE
+)
+
+// ReportExt is the extension of the validation report stored next to a
+// version's .zip in an @v directory.
+const ReportExt = ".zipcheck.json"
+
+// FileError is a problem with one file of a module zip. File is the name in
+// the zip, or empty for problems with the zip as a whole.
+type FileError struct {
+	File  string `json:"file,omitempty"`
+	Error string `json:"error"`
+}
+
+// Report is the outcome of validating a module zip.
+type Report struct {
+	Path      string      `json:"path"`
+	Version   string      `json:"version"`
+	Valid     bool        `json:"valid"`
+	Files     int         `json:"files"`
+	Size      uint64      `json:"size"` // total uncompressed size
+	Errors    []FileError `json:"errors"`
+	CheckedAt time.Time   `json:"checked_at"`
+}
+
+// Err summarizes the report's errors, or returns nil for a valid zip.
+func (r *Report) Err() error {
+	if r.Valid {
+		return nil
+	}
+	first := r.Errors[0].Error
+	if r.Errors[0].File != "" {
+		first = r.Errors[0].File + ": " + first
+	}
+	if len(r.Errors) == 1 {
+		return fmt.Errorf("invalid module zip: %s", first)
+	}
+	return fmt.Errorf("invalid module zip: %s (and %d more problems)", first, len(r.Errors)-1)
+}
+
+func (r *Report) add(file, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, FileError{File: file, Error: fmt.Sprintf(format, args...)})
+}
+
+// Check validates a module zip against the rules the go command applies to
+// module zips: every file is a regular file under the module@version/
+// prefix with a safe, portable path, no two paths differ only in case, the
+// size limits hold, and there are no vendored packages or files belonging to
+// nested modules. Sizes are taken from the zip headers, which the go command
+// also enforces when extracting.
+func Check(zipPath, modPath, version string) (*Report, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", zipPath, err)
+	}
+	defer zr.Close()
+
+	report := &Report{
+		Path:      modPath,
+		Version:   version,
+		Errors:    []FileError{},
+		CheckedAt: time.Now().UTC(),
+	}
+
+	prefix := modPath + "@" + version + "/"
+	folded := make(map[string]string)
+	var names []string
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, prefix)
+		if name == f.Name {
+			report.add(f.Name, "path does not have prefix %q", prefix)
+			continue
+		}
+		isDir := strings.HasSuffix(name, "/")
+		name = strings.TrimSuffix(name, "/")
+		if name == "" {
+			continue
+		}
+		if err := checkFilePath(name); err != nil {
+			report.add(f.Name, "%v", err)
+			continue
+		}
+		if !isDir && !f.Mode().IsRegular() {
+			report.add(f.Name, "not a regular file")
+			continue
+		}
+
+		// Directories may repeat, but a directory and a file, or two files,
+		// must not fold to the same name
+		key := strings.ToLower(name)
+		if other, ok := folded[key]; ok && !(isDir && other == name+"/") {
+			report.add(f.Name, "case-insensitive file name collision with %s", strings.TrimSuffix(other, "/"))
+			continue
+		}
+		if isDir {
+			folded[key] = name + "/"
+			continue
+		}
+		folded[key] = name
+
+		report.Files++
+		report.Size += f.UncompressedSize64
+		switch name {
+		case "go.mod":
+			if f.UncompressedSize64 > MaxGoMod {
+				report.add(f.Name, "go.mod is %d bytes, larger than %d", f.UncompressedSize64, MaxGoMod)
+			}
+		case "LICENSE":
+			if f.UncompressedSize64 > MaxLICENSE {
+				report.add(f.Name, "LICENSE is %d bytes, larger than %d", f.UncompressedSize64, MaxLICENSE)
+			}
+		}
+		names = append(names, name)
+	}
+	if report.Size > MaxZipFile {
+		report.add("", "module is %d bytes uncompressed, larger than %d", report.Size, MaxZipFile)
+	}
+
+	// A go.mod below the root starts a nested module, whose files belong in
+	// that module's own zip
+	var nested []string
+	for _, name := range names {
+		if dir := path.Dir(name); path.Base(name) == "go.mod" && dir != "." {
+			nested = append(nested, dir+"/")
+		}
+	}
+	sort.Strings(nested)
+	for _, name := range names {
+		if isVendoredPackage(name) {
+			report.add(prefix+name, "file in vendored package")
+			continue
+		}
+		for _, dir := range nested {
+			if strings.HasPrefix(name, dir) {
+				report.add(prefix+name, "file in nested module %s", strings.TrimSuffix(dir, "/"))
+				break
+			}
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report, nil
+}
+
+// isVendoredPackage reports whether name is a file in a package under a
+// vendor directory. Files directly in vendor/, such as vendor/modules.txt,
+// are not packages.
+func isVendoredPackage(name string) bool {
+	var i int
+	if strings.HasPrefix(name, "vendor/") {
+		i = len("vendor/")
+	} else if j := strings.Index(name, "/vendor/"); j >= 0 {
+		i = j + len("/vendor/")
+	} else {
+		return false
+	}
+	return strings.Contains(name[i:], "/")
+}
+
+// checkFilePath rejects file paths that could escape the extraction
+// directory or that are not portable across file systems.
+func checkFilePath(name string) error {
+	if strings.HasPrefix(name, "/") {
+		return fmt.Errorf("absolute path")
+	}
+	if strings.Contains(name, "\\") {
+		return fmt.Errorf("backslash in path")
+	}
+	for _, elem := range strings.Split(name, "/") {
+		switch {
+		case elem == "":
+			return fmt.Errorf("empty path element")
+		case elem == "." || elem == "..":
+			return fmt.Errorf("path element %q", elem)
+		case strings.HasSuffix(elem, "."):
+			return fmt.Errorf("path element %q has trailing dot", elem)
+		}
+		for _, r := range elem {
+			if !fileNameOK(r) {
+				return fmt.Errorf("invalid character %q in path", r)
+			}
+		}
+	}
+	return nil
+}
+
+// fileNameOK reports whether r may appear in a module file name. This is
+// the character set golang.org/x/mod/module allows in file paths.
+func fileNameOK(r rune) bool {
+	if r < 0x80 {
+		const allowed = "!#$%&()+,-.=@[]^_{}~ "
+		if '0' <= r && r <= '9' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' {
+			return true
+		}
+		return strings.ContainsRune(allowed, r)
+	}
+	return unicode.IsLetter(r)
+}
+
+// WriteReport writes a validation report as JSON.
+func WriteReport(file string, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,101 @@
+package modzip
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CreateFromDir writes the zip of modPath@version with the files of the
+// module in dir, the way the go command would build it from a repository
+// checkout: version control directories, subdirectories with their own
+// go.mod, vendored packages and files that are not regular files, such as
+// symbolic links, are left out. Files with paths a module zip may not
+// contain are an error, as is a zip that fails Check.
+func CreateFromDir(zipPath, dir, modPath, version string) (err error) {
+	var names []string
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if d.IsDir() {
+			if file == dir {
+				return nil
+			}
+			switch d.Name() {
+			case ".bzr", ".git", ".hg", ".svn":
+				return filepath.SkipDir
+			}
+			if info, err := os.Lstat(filepath.Join(file, "go.mod")); err == nil && !info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || isVendoredPackage(name) {
+			return nil
+		}
+		if err := checkFilePath(name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(zipPath)
+		}
+	}()
+	zw := zip.NewWriter(f)
+	prefix := modPath + "@" + version + "/"
+	for _, name := range names {
+		if err := addFile(zw, prefix+name, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	report, err := Check(zipPath, modPath, version)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+func addFile(zw *zip.Writer, name, file string) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("failed to add %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package modzip
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// HashZip returns the "h1:" hash of a module zip, the same value go.sum
+// records for path@version.
+func HashZip(zipPath string) (string, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	return hashZipReader(&zr.Reader, zipPath)
+}
+
+// HashZipBytes is HashZip for a module zip already in memory.
+func HashZipBytes(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	return hashZipReader(zr, "zip")
+}
+
+func hashZipReader(zr *zip.Reader, name string) (string, error) {
+	files := make(map[string]*zip.File, len(zr.File))
+	var names []string
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		if _, dup := files[f.Name]; dup {
+			return "", fmt.Errorf("duplicate file %s in %s", f.Name, name)
+		}
+		files[f.Name] = f
+		names = append(names, f.Name)
+	}
+
+	return hash1(names, func(name string) (io.ReadCloser, error) {
+		return files[name].Open()
+	})
+}
+
+// HashGoMod returns the "h1:" hash of a go.mod file, the value go.sum records
+// for path@version/go.mod.
+func HashGoMod(modPath string) (string, error) {
+	data, err := os.ReadFile(modPath)
+	if err != nil {
+		return "", err
+	}
+	return HashGoModBytes(data)
+}
+
+// HashGoModBytes is HashGoMod for go.mod contents already in memory.
+func HashGoModBytes(data []byte) (string, error) {
+	return hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(string(data))), nil
+	})
+}
+
+// hash1 implements the h1 directory hash: the SHA-256 of a summary listing
+// the SHA-256 and name of every file, sorted by name.
+func hash1(names []string, open func(string) (io.ReadCloser, error)) (string, error) {
+	sort.Strings(names)
+	summary := sha256.New()
+	for _, name := range names {
+		if strings.Contains(name, "\n") {
+			return "", fmt.Errorf("file name with newline: %q", name)
+		}
+		r, err := open(name)
+		if err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", h.Sum(nil), name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// SHA256File returns the hex encoded SHA-256 of a file's bytes, such as a
+// module zip as it is transferred. Unlike the h1 hash it depends on how the
+// zip was written.
+func SHA256File(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,165 @@
+package modzip
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip creates a zip with the given files, in order, and returns its path.
+func writeZip(t *testing.T, files [][2]string) string {
+	t.Helper()
+	zipPath := filepath.Join(t.TempDir(), "module.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		w, err := zw.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return zipPath
+}
+
+// testModule is example.com/b@v1.0.0; the expected hashes are what
+// go mod download reports for it.
+var testModule = [][2]string{
+	{"example.com/b@v1.0.0/go.mod", "module example.com/b\n\ngo 1.21\n"},
+	{"example.com/b@v1.0.0/LICENSE", "MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy"},
+	{"example.com/b@v1.0.0/b.go", "// Package b does b.\npackage b\n\n// B returns 1.\nfunc B() int { return 1 }\n"},
+}
+
+func TestHashZip(t *testing.T) {
+	h, err := HashZip(writeZip(t, testModule))
+	if err != nil {
+		t.Fatalf("HashZip failed: %v", err)
+	}
+	if want := "h1:CrwycVLIkQiVbiQpiDQUWb331gUL92I6KZyAMNO8sjE="; h != want {
+		t.Errorf("HashZip = %s, want %s", h, want)
+	}
+}
+
+func TestHashGoModBytes(t *testing.T) {
+	h, err := HashGoModBytes([]byte(testModule[0][1]))
+	if err != nil {
+		t.Fatalf("HashGoModBytes failed: %v", err)
+	}
+	if want := "h1:rjc9Jkp/xiv8OktC5V16OuYntMeTjs1dkvTkFbgnZKw="; h != want {
+		t.Errorf("HashGoModBytes = %s, want %s", h, want)
+	}
+}
+
+func TestSHA256File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(file, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := SHA256File(file)
+	if err != nil {
+		t.Fatalf("SHA256File failed: %v", err)
+	}
+	if want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"; got != want {
+		t.Errorf("SHA256File = %s, want %s", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	const prefix = "example.com/b@v1.0.0/"
+	tests := []struct {
+		name  string
+		files [][2]string
+		want  []string // files with errors; "" for the whole zip
+	}{
+		{"valid", append(testModule, [2]string{prefix + "vendor/modules.txt", ""}), nil},
+		{"wrong prefix", [][2]string{{"example.com/b@v1.0.1/b.go", ""}}, []string{"example.com/b@v1.0.1/b.go"}},
+		{"zip slip", [][2]string{{prefix + "../../etc/passwd", ""}}, []string{prefix + "../../etc/passwd"}},
+		{"case collision", [][2]string{{prefix + "README", ""}, {prefix + "readme", ""}}, []string{prefix + "readme"}},
+		{"vendored package", [][2]string{{prefix + "vendor/example.com/c/c.go", ""}}, []string{prefix + "vendor/example.com/c/c.go"}},
+		{"nested module", [][2]string{
+			{prefix + "go.mod", "module example.com/b\n"},
+			{prefix + "sub/go.mod", "module example.com/b/sub\n"},
+			{prefix + "sub/sub.go", "package sub\n"},
+		}, []string{prefix + "sub/go.mod", prefix + "sub/sub.go"}},
+		{"large go.mod", [][2]string{{prefix + "go.mod", strings.Repeat(" ", MaxGoMod+1)}}, []string{prefix + "go.mod"}},
+	}
+
+	for _, tt := range tests {
+		report, err := Check(writeZip(t, tt.files), "example.com/b", "v1.0.0")
+		if err != nil {
+			t.Fatalf("%s: Check failed: %v", tt.name, err)
+		}
+		var got []string
+		for _, e := range report.Errors {
+			got = append(got, e.File)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: errors in %q, want %q (%+v)", tt.name, got, tt.want, report.Errors)
+		}
+		if report.Valid != (len(tt.want) == 0) || (report.Err() == nil) != report.Valid {
+			t.Errorf("%s: Valid = %v with errors %+v", tt.name, report.Valid, report.Errors)
+		}
+	}
+}
+
+func TestCreateFromDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":                    "module example.com/b\n\ngo 1.21\n",
+		"b.go":                      "package b\n",
+		"internal/c/c.go":           "package c\n",
+		"testdata/x.txt":            "x\n",
+		"vendor/modules.txt":        "",
+		"vendor/example.com/d/d.go": "package d\n",
+		".git/config":               "",
+		"sub/go.mod":                "module example.com/b/sub\n",
+		"sub/sub.go":                "package sub\n",
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("b.go", filepath.Join(dir, "link.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath := filepath.Join(t.TempDir(), "v1.0.0.zip")
+	if err := CreateFromDir(zipPath, dir, "example.com/b", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, strings.TrimPrefix(f.Name, "example.com/b@v1.0.0/"))
+	}
+	if got, want := strings.Join(names, " "), "b.go go.mod internal/c/c.go testdata/x.txt vendor/modules.txt"; got != want {
+		t.Errorf("files = %s, want %s", got, want)
+	}
+
+	// Files a module zip may not contain are an error
+	if err := os.WriteFile(filepath.Join(dir, "bad:name.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateFromDir(zipPath, dir, "example.com/b", "v1.0.1"); err == nil {
+		t.Error("CreateFromDir accepted an invalid file name")
+	}
+}
@@ -7,22 +7,99 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
+	"github.com/example/go-mod-clone/internal/crosscheck"
 	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/license"
 	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/policy"
+	"github.com/example/go-mod-clone/internal/quarantine"
 )
 
 type Packer struct {
 	storageRoot string
+	policy      *policy.Policy
+	quarantine  bool
+	verifier    *crosscheck.Verifier
+
+	mu         sync.Mutex
+	violations []policy.Violation
+	checks     []*modzip.Report
+	mismatches []crosscheck.Mismatch
+	staged     int
+}
+
+// Options controls the checks the packer runs before publishing a module.
+type Options struct {
+	Policy     *policy.Policy       // license requirements are checked against the packed zip
+	Quarantine bool                 // stage new versions for approval instead of publishing them
+	CrossCheck *crosscheck.Verifier // hashes must agree with every upstream that has the version
 }
 
 func NewPacker(storageRoot string) *Packer {
-	return &Packer{storageRoot: storageRoot}
+	return NewPackerWithOptions(storageRoot, Options{})
+}
+
+func NewPackerWithOptions(storageRoot string, opts Options) *Packer {
+	return &Packer{
+		storageRoot: storageRoot,
+		policy:      opts.Policy,
+		quarantine:  opts.Quarantine,
+		verifier:    opts.CrossCheck,
+	}
+}
+
+// Violations returns the policy violations found while packing.
+func (p *Packer) Violations() []policy.Violation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]policy.Violation(nil), p.violations...)
+}
+
+// ZipReports returns the validation reports of the zips checked while
+// packing, valid or not.
+func (p *Packer) ZipReports() []*modzip.Report {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*modzip.Report(nil), p.checks...)
+}
+
+// Mismatches returns the files on which the upstreams disagreed while
+// packing.
+func (p *Packer) Mismatches() []crosscheck.Mismatch {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]crosscheck.Mismatch(nil), p.mismatches...)
+}
+
+// Staged returns how many versions were put into quarantine.
+func (p *Packer) Staged() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.staged
+}
+
+// AtVDir returns the @v directory of a module in the storage root.
+func (p *Packer) AtVDir(modPath string) string {
+	return filepath.Join(p.storageRoot, modPath, "@v")
 }
 
 func (p *Packer) Pack(module gomod.Module) error {
+	return p.pack(module, p.quarantine, "")
+}
+
+// Stage puts a new version into the quarantine area for manual review, even
+// if the packer does not quarantine new versions. The reason is recorded in
+// the audit log.
+func (p *Packer) Stage(module gomod.Module, reason string) error {
+	return p.pack(module, true, reason)
+}
+
+func (p *Packer) pack(module gomod.Module, stage bool, reason string) error {
 	// Build target @v directory path
-	atVDir := filepath.Join(p.storageRoot, module.Path, "@v")
+	atVDir := p.AtVDir(module.Path)
 
 	// Check if already exists (idempotent) - check for .zip file
 	targetZip := filepath.Join(atVDir, module.Version+".zip")
@@ -31,8 +108,39 @@ func (p *Packer) Pack(module gomod.Module) error {
 		return nil
 	}
 
+	// New versions go to the quarantine area until they are approved
+	if stage {
+		atVDir = quarantine.AtVDir(p.storageRoot, module.Path)
+		targetZip = filepath.Join(atVDir, module.Version+".zip")
+		if _, err := os.Stat(targetZip); err == nil {
+			log.Info("Module awaiting approval: %s@%s, skipping", module.Path, module.Version)
+			return nil
+		}
+	}
+
 	log.Info("Packing module: %s@%s", module.Path, module.Version)
 
+	// Validate the zip and detect licenses before anything is published, so
+	// that a malformed zip or a license the policy does not allow keeps the
+	// module out of the storage root
+	var check *modzip.Report
+	var licenses *license.Report
+	if module.ZipFile != "" {
+		var err error
+		if check, err = p.checkZip(module); err != nil {
+			return err
+		}
+		if err := p.crossCheck(module); err != nil {
+			return err
+		}
+		if licenses, err = license.DetectZip(module.ZipFile, module.Path, module.Version); err != nil {
+			return fmt.Errorf("failed to detect licenses: %w", err)
+		}
+		if err := p.checkLicenses(module, licenses); err != nil {
+			return err
+		}
+	}
+
 	// Create @v directory
 	if err := os.MkdirAll(atVDir, 0755); err != nil {
 		return fmt.Errorf("failed to create @v directory: %w", err)
@@ -61,15 +169,205 @@ func (p *Packer) Pack(module gomod.Module) error {
 		}
 	}
 
+	if check != nil {
+		if err := modzip.WriteReport(filepath.Join(atVDir, module.Version+modzip.ReportExt), check); err != nil {
+			log.Warn("Failed to write zip validation report for %s@%s: %v", module.Path, module.Version, err)
+		}
+	}
+	if licenses != nil {
+		if err := license.WriteMetadata(filepath.Join(atVDir, module.Version+license.MetadataExt), licenses); err != nil {
+			log.Warn("Failed to write license metadata for %s@%s: %v", module.Path, module.Version, err)
+		}
+	}
+
 	// Update list file (with file locking for concurrent access)
 	if err := p.updateListFile(atVDir, module.Version); err != nil {
 		return fmt.Errorf("failed to update list file: %w", err)
 	}
 
+	if stage {
+		p.mu.Lock()
+		p.staged++
+		p.mu.Unlock()
+		if err := quarantine.Record(p.storageRoot, quarantine.Event{
+			Action:  quarantine.ActionStaged,
+			Path:    module.Path,
+			Version: module.Version,
+			User:    quarantine.CurrentUser(),
+			Reason:  reason,
+		}); err != nil {
+			log.Warn("Failed to write audit log: %v", err)
+		}
+		log.Info("Staged for approval: %s@%s", module.Path, module.Version)
+		return nil
+	}
+
 	log.Debug("Successfully packed: %s@%s", module.Path, module.Version)
 	return nil
 }
 
+// stagedExts are the files a quarantined version may have.
+var stagedExts = []string{".info", ".mod", ".zip", license.MetadataExt, modzip.ReportExt}
+
+// Approve publishes a quarantined version to the storage root and records
+// the approval in the audit log.
+func (p *Packer) Approve(modPath, version, user, reason string) error {
+	stagedDir := quarantine.AtVDir(p.storageRoot, modPath)
+	module := gomod.Module{Path: modPath, Version: version}
+	for _, ext := range []string{".info", ".mod", ".zip"} {
+		file := filepath.Join(stagedDir, version+ext)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		switch ext {
+		case ".info":
+			module.InfoFile = file
+		case ".mod":
+			module.ModFile = file
+		case ".zip":
+			module.ZipFile = file
+		}
+	}
+	if module.ZipFile == "" {
+		return fmt.Errorf("%s@%s is not in quarantine", modPath, version)
+	}
+
+	// Licenses were checked when the version was staged; approving it is
+	// the decision to publish it
+	publisher := NewPacker(p.storageRoot)
+	if err := publisher.Pack(module); err != nil {
+		return err
+	}
+	if err := p.removeStaged(modPath, version); err != nil {
+		return err
+	}
+	return quarantine.Record(p.storageRoot, quarantine.Event{
+		Action:  quarantine.ActionApproved,
+		Path:    modPath,
+		Version: version,
+		User:    user,
+		Reason:  reason,
+	})
+}
+
+// Reject deletes a quarantined version and records the rejection in the
+// audit log.
+func (p *Packer) Reject(modPath, version, user, reason string) error {
+	stagedDir := quarantine.AtVDir(p.storageRoot, modPath)
+	if _, err := os.Stat(filepath.Join(stagedDir, version+".zip")); err != nil {
+		return fmt.Errorf("%s@%s is not in quarantine", modPath, version)
+	}
+	if err := p.removeStaged(modPath, version); err != nil {
+		return err
+	}
+	return quarantine.Record(p.storageRoot, quarantine.Event{
+		Action:  quarantine.ActionRejected,
+		Path:    modPath,
+		Version: version,
+		User:    user,
+		Reason:  reason,
+	})
+}
+
+// removeStaged deletes a version's files from the quarantine area, along
+// with the module's directories once nothing else is staged for it.
+func (p *Packer) removeStaged(modPath, version string) error {
+	stagedDir := quarantine.AtVDir(p.storageRoot, modPath)
+	for _, ext := range stagedExts {
+		if err := os.Remove(filepath.Join(stagedDir, version+ext)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := p.removeFromListFile(stagedDir, version); err != nil {
+		return err
+	}
+
+	// Remove empty directories up to the quarantine area itself
+	root := quarantine.Dir(p.storageRoot)
+	for dir := stagedDir; dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// checkZip validates the module zip, records the report and returns an
+// error if the zip is invalid.
+func (p *Packer) checkZip(module gomod.Module) (*modzip.Report, error) {
+	report, err := modzip.Check(module.ZipFile, module.Path, module.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate zip: %w", err)
+	}
+	p.mu.Lock()
+	p.checks = append(p.checks, report)
+	p.mu.Unlock()
+	for _, e := range report.Errors {
+		log.Warn("Invalid zip %s@%s: %s: %s", module.Path, module.Version, e.File, e.Error)
+	}
+	return report, report.Err()
+}
+
+// crossCheck compares the hashes of the module's files with the configured
+// upstreams and returns an error if any of them disagree.
+func (p *Packer) crossCheck(module gomod.Module) error {
+	if p.verifier == nil {
+		return nil
+	}
+	var local crosscheck.Hashes
+	var err error
+	if module.ModFile != "" {
+		if local.Mod, err = modzip.HashGoMod(module.ModFile); err != nil {
+			return fmt.Errorf("failed to hash go.mod: %w", err)
+		}
+	}
+	if local.Zip, err = modzip.HashZip(module.ZipFile); err != nil {
+		return fmt.Errorf("failed to hash zip: %w", err)
+	}
+
+	mismatches, err := p.verifier.Verify(module.Path, module.Version, local)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	p.mismatches = append(p.mismatches, mismatches...)
+	p.mu.Unlock()
+	for _, m := range mismatches {
+		log.Error("Upstream hash mismatch: %s", m)
+	}
+	return fmt.Errorf("upstreams disagree on the %s hash", mismatches[0].File)
+}
+
+// checkLicenses evaluates the policy's license requirements and returns an
+// error if the module is blocked.
+func (p *Packer) checkLicenses(module gomod.Module, report *license.Report) error {
+	if p.policy == nil {
+		return nil
+	}
+	violations := p.policy.EvaluateLicenses(policy.Subject{
+		Path:     module.Path,
+		Version:  module.Version,
+		Licenses: report.Licenses,
+	})
+	if len(violations) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	p.violations = append(p.violations, violations...)
+	p.mu.Unlock()
+	for _, v := range violations {
+		log.Warn("Policy violation: %s", v)
+	}
+	if policy.Blocking(violations) {
+		return fmt.Errorf("blocked by policy: %s", violations[0].Reason)
+	}
+	return nil
+}
+
 func copyFile(src, dst string) error {
 	source, err := os.Open(src)
 	if err != nil {
@@ -87,6 +385,30 @@ func copyFile(src, dst string) error {
 	return err
 }
 
+// removeFromListFile drops a version from a list file, deleting the file
+// when no versions remain.
+func (p *Packer) removeFromListFile(atVDir, version string) error {
+	listPath := filepath.Join(atVDir, "list")
+	data, err := os.ReadFile(listPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var versionList []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line != version {
+			versionList = append(versionList, line)
+		}
+	}
+	if len(versionList) == 0 {
+		return os.Remove(listPath)
+	}
+	return os.WriteFile(listPath, []byte(strings.Join(versionList, "\n")+"\n"), 0644)
+}
+
 func (p *Packer) updateListFile(atVDir, version string) error {
 	listPath := filepath.Join(atVDir, "list")
 
@@ -129,4 +451,3 @@ func (p *Packer) updateListFile(atVDir, version string) error {
 
 	return nil
 }
-
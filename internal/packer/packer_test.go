@@ -1,9 +1,14 @@
 package packer
 
 import (
+	"archive/zip"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/quarantine"
+	"github.com/example/go-mod-clone/internal/storage"
 )
 
 func TestPacker_BuildTargetPath(t *testing.T) {
@@ -54,3 +59,106 @@ func TestCopyFile(t *testing.T) {
 		t.Errorf("Content mismatch: got %q, want %q", string(dstContent), string(content))
 	}
 }
+
+// writeModuleZip writes a minimal module zip and returns its path.
+func writeModuleZip(t *testing.T, dir, modPath, version string) string {
+	t.Helper()
+	file := filepath.Join(dir, version+".zip")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(modPath + "@" + version + "/go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("module " + modPath + "\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return file
+}
+
+func TestPack_QuarantineApproveReject(t *testing.T) {
+	root := t.TempDir()
+	src := t.TempDir()
+	const modPath = "example.com/a"
+
+	p := NewPackerWithOptions(root, Options{Quarantine: true})
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		mod := gomod.Module{Path: modPath, Version: version, ZipFile: writeModuleZip(t, src, modPath, version)}
+		if err := p.Pack(mod); err != nil {
+			t.Fatalf("Pack %s failed: %v", version, err)
+		}
+	}
+	if p.Staged() != 2 {
+		t.Errorf("Staged() = %d, want 2", p.Staged())
+	}
+	if _, err := os.Stat(filepath.Join(p.AtVDir(modPath), "v1.0.0.zip")); !os.IsNotExist(err) {
+		t.Error("Quarantined version was published")
+	}
+
+	if err := p.Approve(modPath, "v1.0.0", "alice", "reviewed"); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if err := p.Reject(modPath, "v1.1.0", "bob", ""); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if err := p.Approve(modPath, "v1.1.0", "alice", ""); err == nil {
+		t.Error("Approving a rejected version should fail")
+	}
+
+	if got := storage.ReadList(p.AtVDir(modPath)); len(got) != 1 || got[0] != "v1.0.0" {
+		t.Errorf("Published versions = %v, want [v1.0.0]", got)
+	}
+	if pending, _ := quarantine.Pending(root); len(pending) != 0 {
+		t.Errorf("Versions still pending: %+v", pending)
+	}
+	if _, err := os.Stat(filepath.Join(quarantine.Dir(root), "example.com")); !os.IsNotExist(err) {
+		t.Error("Empty quarantine directories were not removed")
+	}
+
+	events, err := quarantine.ReadLog(root)
+	if err != nil {
+		t.Fatalf("ReadLog failed: %v", err)
+	}
+	want := []struct {
+		action  quarantine.Action
+		version string
+		user    string
+	}{
+		{quarantine.ActionStaged, "v1.0.0", ""},
+		{quarantine.ActionStaged, "v1.1.0", ""},
+		{quarantine.ActionApproved, "v1.0.0", "alice"},
+		{quarantine.ActionRejected, "v1.1.0", "bob"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Audit log has %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Action != w.action || e.Version != w.version || (w.user != "" && e.User != w.user) {
+			t.Errorf("Audit event %d = %+v, want %+v", i, e, w)
+		}
+	}
+}
+
+func TestPack_RefusesInvalidZip(t *testing.T) {
+	root := t.TempDir()
+	// A zip of another version fails the module@version/ prefix check
+	zipFile := writeModuleZip(t, t.TempDir(), "example.com/a", "v1.0.1")
+
+	p := NewPacker(root)
+	err := p.Pack(gomod.Module{Path: "example.com/a", Version: "v1.0.0", ZipFile: zipFile})
+	if err == nil {
+		t.Fatal("Pack accepted an invalid zip")
+	}
+	if _, err := os.Stat(p.AtVDir("example.com/a")); !os.IsNotExist(err) {
+		t.Error("Invalid module was written to the storage root")
+	}
+	if reports := p.ZipReports(); len(reports) != 1 || reports[0].Valid {
+		t.Errorf("ZipReports() = %+v, want one invalid report", reports)
+	}
+}
@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+)
+
+// Constraint is a conjunction of version comparisons such as
+// ">=v1.2.0, <v2.0.0". A bare version means "=version".
+type Constraint struct {
+	raw   string
+	terms []term
+}
+
+type term struct {
+	op      string
+	version string
+}
+
+var constraintOps = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses a comma-separated list of comparisons.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t := term{op: "="}
+		for _, op := range constraintOps {
+			if strings.HasPrefix(part, op) {
+				t.op = op
+				part = strings.TrimSpace(part[len(op):])
+				break
+			}
+		}
+		if !gomod.IsCanonicalVersion(part) {
+			return nil, fmt.Errorf("invalid version %q in constraint %q", part, s)
+		}
+		t.version = part
+		c.terms = append(c.terms, t)
+	}
+	if len(c.terms) == 0 {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+	return c, nil
+}
+
+// Check reports whether version satisfies every comparison.
+func (c *Constraint) Check(version string) bool {
+	for _, t := range c.terms {
+		cmp := gomod.CompareVersions(version, t.version)
+		var ok bool
+		switch t.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
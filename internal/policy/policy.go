@@ -0,0 +1,313 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Actions a rule can take on the modules it matches.
+const (
+	ActionAllow   = "allow"   // the module passes the path check
+	ActionDeny    = "deny"    // the module is rejected
+	ActionRequire = "require" // the module must meet the rule's requirements
+)
+
+// Severities of a violation.
+const (
+	SeverityBlock = "block" // the module is not mirrored
+	SeverityWarn  = "warn"  // the module is mirrored and the violation reported
+)
+
+// Rule is one entry of a policy file.
+//
+// Allow and deny rules are checked in order and the first one whose path and
+// version selectors match decides whether the module may be mirrored at all;
+// when none matches, the policy's default action applies. Require rules are
+// all checked, and every unmet requirement of a matching rule is a violation.
+type Rule struct {
+	Name     string   `json:"name"`
+	Action   string   `json:"action"`
+	Paths    []string `json:"paths,omitempty"`    // GOPRIVATE-style glob prefixes
+	Regex    string   `json:"regex,omitempty"`    // regular expression on the module path
+	Versions string   `json:"versions,omitempty"` // semver constraint, e.g. ">=v1.0.0, <v2.0.0"
+	MinAge   string   `json:"min_age,omitempty"`  // require: minimum age of the release, e.g. "72h"
+	Licenses []string `json:"licenses,omitempty"` // require: allowed SPDX license identifiers
+	Severity string   `json:"severity,omitempty"` // block (default) or warn
+
+	re       *regexp.Regexp
+	versions *Constraint
+	minAge   time.Duration
+}
+
+// Policy is a parsed policy file.
+type Policy struct {
+	Default         string  `json:"default"`          // allow (default) or deny
+	DefaultSeverity string  `json:"default_severity"` // severity when the default action denies
+	Rules           []*Rule `json:"rules"`
+}
+
+// Subject is a module version being checked. Zero values mean "unknown";
+// requirements that depend on unknown data are not checked.
+type Subject struct {
+	Path     string
+	Version  string
+	Time     time.Time // release time from the .info file
+	Licenses []string  // detected SPDX identifiers, nil if not detected yet
+}
+
+// Violation is a rule a module version does not comply with.
+type Violation struct {
+	Rule     string `json:"rule"`
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Severity string `json:"severity"`
+	Reason   string `json:"reason"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s@%s: %s (rule %s, %s)", v.Path, v.Version, v.Reason, v.Rule, v.Severity)
+}
+
+// Blocking reports whether any of violations has block severity.
+func Blocking(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Severity == SeverityBlock {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads and validates a JSON policy file.
+func Load(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", file, err)
+	}
+	if err := p.compile(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", file, err)
+	}
+	return &p, nil
+}
+
+func (p *Policy) compile() error {
+	switch p.Default {
+	case "":
+		p.Default = ActionAllow
+	case ActionAllow, ActionDeny:
+	default:
+		return fmt.Errorf("default must be %q or %q, got %q", ActionAllow, ActionDeny, p.Default)
+	}
+	if p.DefaultSeverity == "" {
+		p.DefaultSeverity = SeverityBlock
+	}
+	if err := checkSeverity(p.DefaultSeverity); err != nil {
+		return err
+	}
+
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		switch r.Action {
+		case ActionAllow, ActionDeny, ActionRequire:
+		default:
+			return fmt.Errorf("rule %s: action must be allow, deny or require, got %q", r.Name, r.Action)
+		}
+		if r.Severity == "" {
+			r.Severity = SeverityBlock
+		}
+		if err := checkSeverity(r.Severity); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		for _, pattern := range r.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %s: bad pattern %q: %w", r.Name, pattern, err)
+			}
+		}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+			r.re = re
+		}
+		if r.Versions != "" {
+			c, err := ParseConstraint(r.Versions)
+			if err != nil {
+				return fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+			r.versions = c
+		}
+		if r.MinAge != "" {
+			d, err := time.ParseDuration(r.MinAge)
+			if err != nil {
+				return fmt.Errorf("rule %s: invalid min_age: %w", r.Name, err)
+			}
+			r.minAge = d
+		}
+		if r.Action != ActionRequire && (r.MinAge != "" || len(r.Licenses) > 0) {
+			return fmt.Errorf("rule %s: min_age and licenses need action %q", r.Name, ActionRequire)
+		}
+	}
+	return nil
+}
+
+func checkSeverity(s string) error {
+	if s != SeverityBlock && s != SeverityWarn {
+		return fmt.Errorf("severity must be %q or %q, got %q", SeverityBlock, SeverityWarn, s)
+	}
+	return nil
+}
+
+// matchesPath reports whether the rule selects the module path. A rule
+// without path selectors selects every module.
+func (r *Rule) matchesPath(modPath string) bool {
+	if len(r.Paths) == 0 && r.re == nil {
+		return true
+	}
+	for _, pattern := range r.Paths {
+		if matchPrefixPattern(pattern, modPath) {
+			return true
+		}
+	}
+	return r.re != nil && r.re.MatchString(modPath)
+}
+
+// matchPrefixPattern matches pattern against the leading path elements of
+// modPath, like GOPRIVATE: "github.com/corp" and "*.corp.example" both match
+// every module below them.
+func matchPrefixPattern(pattern, modPath string) bool {
+	n := strings.Count(strings.Trim(pattern, "/"), "/") + 1
+	elems := strings.Split(modPath, "/")
+	if len(elems) < n {
+		return false
+	}
+	ok, _ := path.Match(strings.Trim(pattern, "/"), strings.Join(elems[:n], "/"))
+	return ok
+}
+
+// Evaluate returns every violation of s against the policy.
+func (p *Policy) Evaluate(s Subject) []Violation {
+	var violations []Violation
+	p.checkPath(s, &violations)
+	p.checkRequirements(s, &violations, false)
+	return violations
+}
+
+// EvaluateLicenses returns only the violations of the license requirements.
+// It is used once a module's licenses are known, after the other rules were
+// already checked during resolution.
+func (p *Policy) EvaluateLicenses(s Subject) []Violation {
+	var violations []Violation
+	p.checkRequirements(s, &violations, true)
+	return violations
+}
+
+func newViolation(s Subject, rule, severity, reason string) Violation {
+	return Violation{
+		Rule:     rule,
+		Path:     s.Path,
+		Version:  s.Version,
+		Severity: severity,
+		Reason:   reason,
+	}
+}
+
+// checkPath applies the first matching allow or deny rule, or the default.
+func (p *Policy) checkPath(s Subject, violations *[]Violation) {
+	violation := func(rule, severity, reason string) {
+		*violations = append(*violations, newViolation(s, rule, severity, reason))
+	}
+
+	decided := false
+	for _, r := range p.Rules {
+		if r.Action == ActionRequire || !r.matchesPath(s.Path) {
+			continue
+		}
+		if r.versions != nil && !r.versions.Check(s.Version) {
+			continue
+		}
+		if r.Action == ActionDeny {
+			violation(r.Name, r.Severity, "denied by policy")
+		}
+		decided = true
+		break
+	}
+	if !decided && p.Default == ActionDeny {
+		violation("default", p.DefaultSeverity, "not allowed by any rule")
+	}
+}
+
+// checkRequirements applies every matching require rule. With licensesOnly
+// set, only the license allow-lists are checked.
+func (p *Policy) checkRequirements(s Subject, violations *[]Violation, licensesOnly bool) {
+	violation := func(rule, severity, reason string) {
+		*violations = append(*violations, newViolation(s, rule, severity, reason))
+	}
+
+	for _, r := range p.Rules {
+		if r.Action != ActionRequire || !r.matchesPath(s.Path) {
+			continue
+		}
+		if !licensesOnly && r.versions != nil && !r.versions.Check(s.Version) {
+			violation(r.Name, r.Severity, fmt.Sprintf("version does not satisfy %s", r.versions))
+		}
+		if !licensesOnly && r.minAge > 0 && !s.Time.IsZero() {
+			if age := time.Since(s.Time); age < r.minAge {
+				violation(r.Name, r.Severity, fmt.Sprintf("released %v ago, minimum age is %v", age.Round(time.Minute), r.minAge))
+			}
+		}
+		if len(r.Licenses) > 0 && s.Licenses != nil && !licenseAllowed(r.Licenses, s.Licenses) {
+			found := "none"
+			if len(s.Licenses) > 0 {
+				found = strings.Join(s.Licenses, ", ")
+			}
+			violation(r.Name, r.Severity, fmt.Sprintf("license %s is not in the allow-list", found))
+		}
+	}
+}
+
+// licenseAllowed reports whether every detected license is allowed. A module
+// without any detected license is not allowed.
+func licenseAllowed(allowed, found []string) bool {
+	if len(found) == 0 {
+		return false
+	}
+	for _, f := range found {
+		ok := false
+		for _, a := range allowed {
+			if strings.EqualFold(a, f) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteReport writes violations as JSON to file.
+func WriteReport(file string, violations []Violation) error {
+	if violations == nil {
+		violations = []Violation{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Violations []Violation `json:"violations"`
+	}{violations}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testPolicy = `{
+  "default": "deny",
+  "rules": [
+    {"name": "no-evil", "action": "deny", "paths": ["github.com/evil"]},
+    {"name": "old-yaml", "action": "deny", "paths": ["gopkg.in/yaml.v2"], "versions": "<v2.4.0", "severity": "warn"},
+    {"name": "github", "action": "allow", "paths": ["github.com/*"]},
+    {"name": "corp", "action": "allow", "regex": "^([a-z]+\\.)?corp\\.example\\.com/"},
+    {"name": "yaml", "action": "allow", "paths": ["gopkg.in/yaml.v2"]},
+    {"name": "corp-v1", "action": "require", "regex": "corp\\.example\\.com/", "versions": ">=v1.0.0, <v2.0.0"},
+    {"name": "cooldown", "action": "require", "min_age": "72h", "severity": "warn"},
+    {"name": "licenses", "action": "require", "licenses": ["MIT", "Apache-2.0"]}
+  ]
+}`
+
+func loadTestPolicy(t *testing.T) *Policy {
+	file := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(file, []byte(testPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(file)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return p
+}
+
+func TestEvaluate(t *testing.T) {
+	p := loadTestPolicy(t)
+	old := time.Now().Add(-30 * 24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		subject Subject
+		rules   []string
+	}{
+		{"allowed github module", Subject{Path: "github.com/gin-gonic/gin", Version: "v1.9.1", Time: old}, nil},
+		{"denied org", Subject{Path: "github.com/evil/pkg", Version: "v1.0.0", Time: old}, []string{"no-evil"}},
+		{"default deny", Subject{Path: "example.org/x", Version: "v1.0.0", Time: old}, []string{"default"}},
+		{"denied version range", Subject{Path: "gopkg.in/yaml.v2", Version: "v2.3.0", Time: old}, []string{"old-yaml"}},
+		{"allowed version outside deny range", Subject{Path: "gopkg.in/yaml.v2", Version: "v2.4.0", Time: old}, nil},
+		{"corp v2 fails constraint", Subject{Path: "git.corp.example.com/lib", Version: "v2.0.0", Time: old}, []string{"corp-v1"}},
+		{"too new", Subject{Path: "github.com/a/b", Version: "v1.0.0", Time: time.Now()}, []string{"cooldown"}},
+		{"unknown time is not checked", Subject{Path: "github.com/a/b", Version: "v1.0.0"}, nil},
+		{"license not allowed", Subject{Path: "github.com/a/b", Version: "v1.0.0", Time: old, Licenses: []string{"GPL-3.0"}}, []string{"licenses"}},
+		{"missing license", Subject{Path: "github.com/a/b", Version: "v1.0.0", Time: old, Licenses: []string{}}, []string{"licenses"}},
+		{"license allowed", Subject{Path: "github.com/a/b", Version: "v1.0.0", Time: old, Licenses: []string{"mit"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := p.Evaluate(tt.subject)
+			if len(violations) != len(tt.rules) {
+				t.Fatalf("Got violations %v, want rules %v", violations, tt.rules)
+			}
+			for i, v := range violations {
+				if v.Rule != tt.rules[i] {
+					t.Errorf("Violation %d: got rule %s, want %s", i, v.Rule, tt.rules[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	p := loadTestPolicy(t)
+
+	warn := p.Evaluate(Subject{Path: "gopkg.in/yaml.v2", Version: "v2.3.0"})
+	if Blocking(warn) {
+		t.Errorf("Warn-only violations should not block: %v", warn)
+	}
+	block := p.Evaluate(Subject{Path: "github.com/evil/pkg", Version: "v1.0.0"})
+	if !Blocking(block) {
+		t.Errorf("Deny rule should block: %v", block)
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	c, err := ParseConstraint(">=v1.2.0, <v2.0.0, !=v1.5.0")
+	if err != nil {
+		t.Fatalf("ParseConstraint failed: %v", err)
+	}
+	for version, want := range map[string]bool{
+		"v1.1.9": false,
+		"v1.2.0": true,
+		"v1.5.0": false,
+		"v1.9.9": true,
+		"v2.0.0": false,
+	} {
+		if got := c.Check(version); got != want {
+			t.Errorf("Check(%s) = %v, want %v", version, got, want)
+		}
+	}
+
+	if _, err := ParseConstraint(">=1.2"); err == nil {
+		t.Error("Expected error for non-canonical version")
+	}
+}
+
+func TestEvaluateLicenses(t *testing.T) {
+	p := loadTestPolicy(t)
+
+	// Path and age rules are left to Evaluate
+	s := Subject{Path: "github.com/evil/pkg", Version: "v1.0.0", Time: time.Now(), Licenses: []string{"MIT"}}
+	if v := p.EvaluateLicenses(s); len(v) != 0 {
+		t.Errorf("Expected no license violations, got %v", v)
+	}
+
+	s.Licenses = []string{"MIT", "GPL-3.0"}
+	if v := p.EvaluateLicenses(s); len(v) != 1 || v[0].Rule != "licenses" {
+		t.Errorf("Expected one license violation, got %v", v)
+	}
+}
@@ -0,0 +1,137 @@
+package quarantine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/storage"
+)
+
+// DirName is the storage root directory holding versions that await
+// approval. Its leading underscore keeps it out of module listings and the
+// server refuses to serve it.
+const DirName = "_quarantine"
+
+// Dir returns the quarantine area of a storage root.
+func Dir(storageRoot string) string {
+	return filepath.Join(storageRoot, DirName)
+}
+
+// AtVDir returns the staging @v directory of a module.
+func AtVDir(storageRoot, modPath string) string {
+	return filepath.Join(Dir(storageRoot), modPath, "@v")
+}
+
+// Pending returns the modules with versions awaiting approval.
+func Pending(storageRoot string) ([]storage.Module, error) {
+	modules, err := storage.ListModules(Dir(storageRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pending := modules[:0]
+	for _, m := range modules {
+		if len(m.Versions) > 0 {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Action is what happened to a quarantined version.
+type Action string
+
+const (
+	ActionStaged   Action = "staged"
+	ActionApproved Action = "approved"
+	ActionRejected Action = "rejected"
+)
+
+// Event is one line of the audit log.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Action  Action    `json:"action"`
+	Path    string    `json:"path"`
+	Version string    `json:"version"`
+	User    string    `json:"user"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// AuditFile returns the audit log of a storage root.
+func AuditFile(storageRoot string) string {
+	return filepath.Join(Dir(storageRoot), "audit.log")
+}
+
+var auditMu sync.Mutex
+
+// Record appends an event to the audit log as a JSON line. A zero Time is
+// set to the current time.
+func Record(storageRoot string, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if err := os.MkdirAll(Dir(storageRoot), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(AuditFile(storageRoot), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadLog returns the events in the audit log, oldest first.
+func ReadLog(storageRoot string) ([]Event, error) {
+	f, err := os.Open(AuditFile(storageRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// CurrentUser returns the name recorded for actions of the current process.
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,142 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+)
+
+// cacheSchemaVersion is bumped whenever the layout of ResolutionCache changes;
+// caches written with another schema are discarded on load.
+const cacheSchemaVersion = 2
+
+// DefaultQueryTTL is how long a cached resolution of a query spec such as
+// "@latest" or a bare module path stays valid.
+const DefaultQueryTTL = 24 * time.Hour
+
+// ResolutionCache stores the resolution of every module spec seen so far,
+// keyed by CacheKey. Entries for canonical versions never expire because
+// path@version is immutable; entries for queries expire after the query TTL.
+type ResolutionCache struct {
+	Schema  int                    `json:"schema"`
+	Entries map[string]*CacheEntry `json:"entries"`
+}
+
+// CacheEntry is the resolution of a single module spec.
+type CacheEntry struct {
+	Path      string             `json:"path"`
+	Query     string             `json:"query"`      // version as written in the spec
+	Version   string             `json:"version"`    // version the query resolved to
+	Requires  []gomod.ModuleSpec `json:"requires"`   // direct requirements from go.mod
+	BuildList []gomod.ModuleSpec `json:"build_list"` // modules selected by go list -m all
+	CachedAt  time.Time          `json:"cached_at"`
+}
+
+func newResolutionCache() *ResolutionCache {
+	return &ResolutionCache{
+		Schema:  cacheSchemaVersion,
+		Entries: make(map[string]*CacheEntry),
+	}
+}
+
+// cacheKey hashes a module spec together with every resolver setting that can
+// change its resolution, so that switching GOPROXY or GOFLAGS never reuses an
+// entry produced under different settings.
+func (r *Resolver) cacheKey(spec gomod.ModuleSpec) string {
+	h := sha256.New()
+	h.Write([]byte(spec.Path + "@" + spec.Version + "\n"))
+	h.Write([]byte(r.settings))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookupCache returns the cached entry for spec, or nil when there is none or
+// it has expired.
+func (r *Resolver) lookupCache(spec gomod.ModuleSpec) *CacheEntry {
+	if r.cache == nil {
+		return nil
+	}
+	entry, ok := r.cache.Entries[r.cacheKey(spec)]
+	if !ok {
+		return nil
+	}
+	if r.isExpired(entry) {
+		log.Debug("Cache entry expired: %s@%s", spec.Path, spec.Version)
+		return nil
+	}
+	return entry
+}
+
+func (r *Resolver) storeCache(spec gomod.ModuleSpec, entry *CacheEntry) {
+	if r.cache == nil {
+		return
+	}
+	r.cache.Entries[r.cacheKey(spec)] = entry
+}
+
+func (r *Resolver) isExpired(entry *CacheEntry) bool {
+	if gomod.IsCanonicalVersion(entry.Query) {
+		return false
+	}
+	return time.Since(entry.CachedAt) > r.queryTTL
+}
+
+// loadCache reads the cache file, falling back to an empty cache when it is
+// missing, unreadable or written with another schema.
+func (r *Resolver) loadCache() {
+	if !r.useCache {
+		log.Debug("Cache disabled, skipping cache load")
+		return
+	}
+	r.cache = newResolutionCache()
+
+	data, err := os.ReadFile(r.cacheFile)
+	if err != nil {
+		log.Debug("Cache file not found or unreadable: %v", err)
+		return
+	}
+
+	var cache ResolutionCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Error("Failed to parse cache file: %v", err)
+		return
+	}
+	if cache.Schema != cacheSchemaVersion || cache.Entries == nil {
+		log.Info("Cache invalidated: schema %d, want %d", cache.Schema, cacheSchemaVersion)
+		return
+	}
+
+	r.cache = &cache
+	log.Info("Loaded resolution cache from %s (%d entries)", r.cacheFile, len(cache.Entries))
+}
+
+// saveCache writes the cache file, dropping expired entries.
+func (r *Resolver) saveCache() error {
+	if r.cache == nil {
+		return nil
+	}
+
+	for key, entry := range r.cache.Entries {
+		if r.isExpired(entry) {
+			delete(r.cache.Entries, key)
+		}
+	}
+
+	data, err := json.MarshalIndent(r.cache, "", "  ")
+	if err != nil {
+		log.Error("Failed to marshal cache: %v", err)
+		return err
+	}
+
+	if err := os.WriteFile(r.cacheFile, data, 0644); err != nil {
+		log.Error("Failed to write cache file: %v", err)
+		return err
+	}
+
+	log.Info("Saved resolution cache to %s (%d entries)", r.cacheFile, len(r.cache.Entries))
+	return nil
+}
@@ -0,0 +1,239 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/retry"
+)
+
+// SkippedVersion is a version the resolver refused to select because it was
+// released less than the minimum age ago.
+type SkippedVersion struct {
+	Path     string    `json:"path"`
+	Version  string    `json:"version"`
+	Time     time.Time `json:"time"`
+	Query    string    `json:"query,omitempty"`    // query that selected the version, if any
+	Fallback string    `json:"fallback,omitempty"` // older version selected instead, if any
+}
+
+// Skipped returns the versions skipped for being too new during the last
+// call to ResolveDependencies.
+func (r *Resolver) Skipped() []SkippedVersion {
+	return r.skipped
+}
+
+// tooNew reports whether a release time is within the minimum age. Unknown
+// times are not held back.
+func (r *Resolver) tooNew(t time.Time) bool {
+	return r.minAge > 0 && !t.IsZero() && time.Since(t) < r.minAge
+}
+
+func (r *Resolver) recordSkipped(s SkippedVersion) {
+	if s.Fallback != "" {
+		log.Warn("Skipping %s@%s released %s, within the minimum age; using %s", s.Path, s.Version, s.Time.Format(time.RFC3339), s.Fallback)
+	} else {
+		log.Warn("Skipping %s@%s released %s, within the minimum age", s.Path, s.Version, s.Time.Format(time.RFC3339))
+	}
+	r.skipped = append(r.skipped, s)
+}
+
+// queryInfo is the subset of go list -m -json output the cooldown needs.
+type queryInfo struct {
+	Path     string
+	Version  string
+	Versions []string
+	Time     time.Time
+}
+
+// listModule runs go list -m -json with args, retrying network errors.
+func (r *Resolver) listModule(args ...string) (*queryInfo, error) {
+	var info queryInfo
+	err := r.retry.Do("List "+strings.Join(args, " "), func() error {
+		cmd := r.goCommand(r.workDir, append([]string{"list", "-m", "-json"}, args...)...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			path, version := args[len(args)-1], ""
+			if i := strings.LastIndex(path, "@"); i > 0 {
+				path, version = path[:i], path[i+1:]
+			}
+			rerr := newResolveError(path, version, stderr.String(), fmt.Errorf("go list -m %s failed: %w", strings.Join(args, " "), err))
+			if rerr.Kind != KindNetwork {
+				return retry.Permanent(rerr)
+			}
+			return rerr
+		}
+		return json.Unmarshal(stdout.Bytes(), &info)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// applyCooldown pins a query spec to the version it selects, or, when that
+// version is within the minimum age, to the newest older version that still
+// satisfies the query and is old enough. Canonical versions are returned
+// unchanged; they are checked once their .info files are downloaded.
+func (r *Resolver) applyCooldown(spec gomod.ModuleSpec) (gomod.ModuleSpec, error) {
+	if r.minAge <= 0 || gomod.IsCanonicalVersion(spec.Version) {
+		return spec, nil
+	}
+	query := spec.Version
+	if query == "" {
+		query = "latest"
+	}
+	selected, err := r.listModule(spec.Path + "@" + query)
+	if err != nil {
+		return spec, err
+	}
+	if !r.tooNew(selected.Time) {
+		return gomod.ModuleSpec{Path: spec.Path, Version: selected.Version}, nil
+	}
+
+	skipped := SkippedVersion{Path: spec.Path, Version: selected.Version, Time: selected.Time, Query: spec.Version}
+	all, err := r.listModule("-versions", spec.Path)
+	if err != nil {
+		return spec, err
+	}
+	fallback, err := pickFallback(query, selected.Version, all.Versions, func(version string) (time.Time, error) {
+		info, err := r.listModule(spec.Path + "@" + version)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return info.Time, nil
+	}, r.tooNew)
+	if err != nil {
+		return spec, err
+	}
+	skipped.Fallback = fallback
+	r.recordSkipped(skipped)
+	if fallback == "" {
+		return spec, errNoFallback
+	}
+	return gomod.ModuleSpec{Path: spec.Path, Version: fallback}, nil
+}
+
+// errNoFallback is returned by applyCooldown when every version satisfying
+// a query is within the minimum age.
+var errNoFallback = errors.New("no version old enough satisfies the query")
+
+// errTooNew is the failure of a requested version within the minimum age.
+var errTooNew = errors.New("released within the minimum age")
+
+// tooNewError reports a requested module that cannot be resolved because of
+// the minimum age.
+func tooNewError(path, version string, err error) *ResolveError {
+	return &ResolveError{
+		Path:    path,
+		Version: version,
+		Kind:    KindTooNew,
+		Message: err.Error(),
+		Err:     err,
+	}
+}
+
+// releaseTime returns the release time of path@version from its .info file
+// in the module cache, asking the go command if it is not there, as with
+// versions resolved by an earlier run. Unknown times are zero.
+func (r *Resolver) releaseTime(path, version string) time.Time {
+	mod := gomod.Module{Path: path, Version: version}
+	r.setCacheFiles(&mod)
+	if t := readInfoTime(mod.InfoFile); !t.IsZero() {
+		return t
+	}
+	info, err := r.listModule(path + "@" + version)
+	if err != nil {
+		log.Debug("No release time of %s@%s: %v", path, version, err)
+		return time.Time{}
+	}
+	return info.Time
+}
+
+// pickFallback returns the newest version below selected that satisfies
+// query and is not too new, or "" if there is none. Prereleases are only
+// considered when the query itself selected a prerelease.
+func pickFallback(query, selected string, versions []string, timeOf func(string) (time.Time, error), tooNew func(time.Time) bool) (string, error) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if gomod.CompareVersions(v, selected) >= 0 || !satisfiesQuery(query, v) {
+			continue
+		}
+		if gomod.IsPrerelease(v) && !gomod.IsPrerelease(selected) {
+			continue
+		}
+		t, err := timeOf(v)
+		if err != nil {
+			return "", err
+		}
+		if !tooNew(t) {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+// satisfiesQuery reports whether version matches a go version query. Only
+// queries that select among released versions are understood; a branch or
+// revision query has no older version that satisfies it.
+func satisfiesQuery(query, version string) bool {
+	switch {
+	case query == "latest" || query == "upgrade" || query == "patch":
+		return true
+	case strings.HasPrefix(query, "<="):
+		return gomod.CompareVersions(version, query[2:]) <= 0
+	case strings.HasPrefix(query, ">="):
+		return gomod.CompareVersions(version, query[2:]) >= 0
+	case strings.HasPrefix(query, "<"):
+		return gomod.CompareVersions(version, query[1:]) < 0
+	case strings.HasPrefix(query, ">"):
+		return gomod.CompareVersions(version, query[1:]) > 0
+	case isVersionPrefix(query):
+		return version == query || strings.HasPrefix(version, query+".")
+	}
+	return false
+}
+
+// isVersionPrefix reports whether query is a major or minor version prefix
+// such as "v1" or "v1.2".
+func isVersionPrefix(query string) bool {
+	if !strings.HasPrefix(query, "v") {
+		return false
+	}
+	parts := strings.Split(query[1:], ".")
+	if len(parts) > 2 {
+		return false
+	}
+	for _, p := range parts {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// applyMinAge drops the modules released within the minimum age that the
+// walk did not check itself, such as build list entries that failed to
+// resolve on their own, now that their .info files are available.
+func (r *Resolver) applyMinAge(mods []gomod.Module) []gomod.Module {
+	if r.minAge <= 0 {
+		return mods
+	}
+	result := mods[:0]
+	for _, mod := range mods {
+		if t := readInfoTime(mod.InfoFile); r.tooNew(t) {
+			r.recordSkipped(SkippedVersion{Path: mod.Path, Version: mod.Version, Time: t})
+			continue
+		}
+		result = append(result, mod)
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrorKind classifies why a module could not be resolved.
+type ErrorKind string
+
+const (
+	KindUnknown          ErrorKind = "unknown"
+	KindNotFound         ErrorKind = "not_found"
+	KindNetwork          ErrorKind = "network"
+	KindChecksumMismatch ErrorKind = "checksum_mismatch"
+	KindInvalidGoMod     ErrorKind = "invalid_go_mod"
+	KindTooNew           ErrorKind = "too_new" // within the minimum age
+)
+
+// ResolveError is the failure to resolve or download one module spec.
+type ResolveError struct {
+	Path    string    `json:"path"`
+	Version string    `json:"version"`
+	Kind    ErrorKind `json:"kind"`
+	Message string    `json:"error"`
+	Detail  string    `json:"detail,omitempty"` // go command stderr, if any
+	Err     error     `json:"-"`
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("%s@%s: %s: %s", e.Path, e.Version, e.Kind, e.Message)
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// newResolveError classifies a failed go command by its stderr output.
+func newResolveError(path, version, stderr string, err error) *ResolveError {
+	return &ResolveError{
+		Path:    path,
+		Version: version,
+		Kind:    classifyGoError(stderr + "\n" + err.Error()),
+		Message: err.Error(),
+		Detail:  strings.TrimSpace(stderr),
+		Err:     err,
+	}
+}
+
+// errorPatterns maps go command output fragments to error kinds. Patterns are
+// tried in order, so the more specific kinds come first: a checksum mismatch
+// reported while downloading must not be mistaken for a network error.
+var errorPatterns = []struct {
+	kind     ErrorKind
+	patterns []string
+}{
+	{KindChecksumMismatch, []string{
+		"SECURITY ERROR",
+		"checksum mismatch",
+		"verifying module",
+		"verifying go.mod",
+	}},
+	{KindInvalidGoMod, []string{
+		"errors parsing go.mod",
+		"parsing go.mod",
+		"go.mod has post-",
+		"module declares its path as",
+		"malformed module path",
+		"invalid go version",
+	}},
+	{KindNotFound, []string{
+		"404 Not Found",
+		"410 Gone",
+		"unknown revision",
+		"no matching versions",
+		"repository not found",
+		"Repository not found",
+		"cannot find module",
+		"module lookup disabled",
+		"invalid version",
+		"server response: not found",
+	}},
+	{KindNetwork, []string{
+		"dial tcp",
+		"i/o timeout",
+		"connection refused",
+		"connection reset",
+		"no such host",
+		"TLS handshake timeout",
+		"unexpected EOF",
+		"500 Internal Server Error",
+		"502 Bad Gateway",
+		"503 Service Unavailable",
+		"504 Gateway Timeout",
+		"429 Too Many Requests",
+		"timeout",
+	}},
+}
+
+func classifyGoError(output string) ErrorKind {
+	for _, group := range errorPatterns {
+		for _, p := range group.patterns {
+			if strings.Contains(output, p) {
+				return group.kind
+			}
+		}
+	}
+	return KindUnknown
+}
+
+// FailureError is returned by ResolveDependencies in strict mode when at least
+// one module could not be resolved.
+type FailureError struct {
+	Failures []*ResolveError
+}
+
+func (e *FailureError) Error() string {
+	return fmt.Sprintf("%d modules failed to resolve", len(e.Failures))
+}
+
+// FailureReport is the machine-readable form of a run's resolution failures.
+type FailureReport struct {
+	Failures []*ResolveError `json:"failures"`
+	Counts   map[string]int  `json:"counts"`
+}
+
+// WriteFailureReport writes failures as JSON to path.
+func WriteFailureReport(path string, failures []*ResolveError) error {
+	report := FailureReport{
+		Failures: failures,
+		Counts:   make(map[string]int),
+	}
+	if report.Failures == nil {
+		report.Failures = []*ResolveError{}
+	}
+	for _, f := range failures {
+		report.Counts[string(f.Kind)]++
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
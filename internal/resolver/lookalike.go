@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"sort"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/lookalike"
+)
+
+// Lookalikes returns the modules of the last call to ResolveDependencies
+// whose paths resemble a known module path. They are still returned by
+// ResolveDependencies; holding them back is up to the caller.
+func (r *Resolver) Lookalikes() []lookalike.Finding {
+	return r.lookalikes
+}
+
+// checkLookalikes compares the path of every resolved module against the
+// known paths and records which modules.txt root pulled each match in.
+func (r *Resolver) checkLookalikes(mods []gomod.Module) {
+	r.lookalikes = nil
+	if r.lookalike == nil {
+		return
+	}
+
+	versions := make(map[string][]string)
+	for _, mod := range mods {
+		versions[mod.Path] = append(versions[mod.Path], mod.Version)
+	}
+	paths := make([]string, 0, len(versions))
+	for path := range versions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		vs := versions[path]
+		sort.Slice(vs, func(i, j int) bool { return gomod.CompareVersions(vs[i], vs[j]) < 0 })
+		for _, f := range r.lookalike.Check(path) {
+			f.Versions = vs
+			for _, v := range vs {
+				if chains := r.graph.ShortestChains(path+"@"+v, 1); len(chains) > 0 {
+					f.Chain = chains[0]
+					f.Root = chains[0][0]
+					break
+				}
+			}
+			log.Warn("Lookalike module path: %s, pulled in by %s", f, f.Root)
+			r.lookalikes = append(r.lookalikes, f)
+		}
+	}
+}
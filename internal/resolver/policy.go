@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/policy"
+)
+
+// Violations returns the policy violations found by the last call to
+// ResolveDependencies.
+func (r *Resolver) Violations() []policy.Violation {
+	return r.violations
+}
+
+// checkPolicy evaluates s against the policy, records new violations and
+// reports whether s is blocked.
+func (r *Resolver) checkPolicy(s policy.Subject) bool {
+	if r.policy == nil {
+		return false
+	}
+	violations := r.policy.Evaluate(s)
+	for _, v := range violations {
+		key := v.Rule + "|" + v.Path + "@" + v.Version
+		if r.seenViolations[key] {
+			continue
+		}
+		r.seenViolations[key] = true
+		log.Warn("Policy violation: %s", v)
+		r.violations = append(r.violations, v)
+	}
+	return policy.Blocking(violations)
+}
+
+// applyPolicy drops the modules the policy blocks, now that their .info
+// files, and with them the release times, are available.
+func (r *Resolver) applyPolicy(mods []gomod.Module) []gomod.Module {
+	if r.policy == nil {
+		return mods
+	}
+	result := mods[:0]
+	for _, mod := range mods {
+		s := policy.Subject{
+			Path:    mod.Path,
+			Version: mod.Version,
+			Time:    readInfoTime(mod.InfoFile),
+		}
+		if r.checkPolicy(s) {
+			log.Info("Blocked by policy: %s@%s", mod.Path, mod.Version)
+			continue
+		}
+		result = append(result, mod)
+	}
+	return result
+}
+
+// readInfoTime returns the release time recorded in a .info file, or the
+// zero time if it cannot be read.
+func readInfoTime(file string) time.Time {
+	if file == "" {
+		return time.Time{}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return time.Time{}
+	}
+	var info struct {
+		Time time.Time
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return time.Time{}
+	}
+	return info.Time
+}
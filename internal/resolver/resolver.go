@@ -3,29 +3,82 @@ package resolver
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/graph"
 	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/lookalike"
+	"github.com/example/go-mod-clone/internal/policy"
+	"github.com/example/go-mod-clone/internal/retry"
 )
 
 type Resolver struct {
-	workDir   string
-	cacheFile string
-	useCache  bool
+	workDir    string
+	cacheFile  string
+	useCache   bool
+	modCache   string
+	goPath     string
+	env        []string
+	settings   string
+	queryTTL   time.Duration
+	cache      *ResolutionCache
+	strict     bool
+	failures   []*ResolveError
+	retry      retry.Policy
+	graph      *graph.Graph
+	policy     *policy.Policy
+	minAge     time.Duration
+	skipped    []SkippedVersion
+	lookalike  *lookalike.Checker
+	lookalikes []lookalike.Finding
+
+	violations     []policy.Violation
+	seenViolations map[string]bool
 }
 
-// ResolutionCache stores resolved modules with metadata
-type ResolutionCache struct {
-	Version       string              `json:"version"`
-	CachedAt      time.Time           `json:"cached_at"`
-	Modules       []gomod.Module      `json:"modules"`
-	InputSpecs    []gomod.ModuleSpec  `json:"input_specs"`
-	InputChecksum string              `json:"input_checksum"`
+// Options controls the resolver's cache and the environment handed to every
+// go command it runs. The go commands never see the caller's GOPROXY, GOFLAGS,
+// GOPRIVATE, GONOSUMDB or module cache; only the values given here.
+type Options struct {
+	UseCache  bool
+	GoProxy   string             // GOPROXY, defaults to DefaultGoProxy
+	GoNoSumDB string             // GONOSUMDB
+	GoPrivate string             // GOPRIVATE
+	GoFlags   string             // GOFLAGS
+	QueryTTL  time.Duration      // cache lifetime of query specs, defaults to DefaultQueryTTL
+	Strict    bool               // fail ResolveDependencies if any module fails to resolve
+	Retry     retry.Policy       // retries of go commands, defaults to retry.DefaultPolicy
+	Policy    *policy.Policy     // modules the policy blocks are not resolved or returned
+	MinAge    time.Duration      // versions released more recently are not selected
+	Lookalike *lookalike.Checker // flags module paths resembling known paths
+}
+
+// DefaultGoProxy is the GOPROXY used when Options.GoProxy is empty.
+const DefaultGoProxy = "https://proxy.golang.org,direct"
+
+// strippedGoVars are inherited variables that would leak the caller's go
+// configuration into the resolver's go commands.
+var strippedGoVars = []string{
+	"GOENV",
+	"GOFLAGS",
+	"GOINSECURE",
+	"GOMODCACHE",
+	"GONOPROXY",
+	"GONOSUMDB",
+	"GOPATH",
+	"GOPRIVATE",
+	"GOPROXY",
+	"GOSUMDB",
+	"GOVCS",
+	"GOWORK",
+	"GO111MODULE",
 }
 
 type modInfo struct {
@@ -40,104 +93,170 @@ type modInfo struct {
 }
 
 func NewResolver(workDir string) *Resolver {
-	return &Resolver{
-		workDir:   workDir,
-		cacheFile: filepath.Join(workDir, "resolution-cache.json"),
-		useCache:  true,
-	}
+	return NewResolverWithOptions(workDir, Options{UseCache: true})
 }
 
 func NewResolverWithCacheControl(workDir string, useCache bool) *Resolver {
-	return &Resolver{
+	return NewResolverWithOptions(workDir, Options{UseCache: useCache})
+}
+
+func NewResolverWithOptions(workDir string, opts Options) *Resolver {
+	// Cache entries are keyed by the settings in effect
+	if opts.GoProxy == "" {
+		opts.GoProxy = DefaultGoProxy
+	}
+	// GOPATH and GOMODCACHE must be absolute
+	if abs, err := filepath.Abs(workDir); err == nil {
+		workDir = abs
+	}
+	r := &Resolver{
 		workDir:   workDir,
 		cacheFile: filepath.Join(workDir, "resolution-cache.json"),
-		useCache:  useCache,
+		useCache:  opts.UseCache,
+		modCache:  filepath.Join(workDir, "modcache"),
+		goPath:    filepath.Join(workDir, "gopath"),
+		queryTTL:  opts.QueryTTL,
+		strict:    opts.Strict,
+		retry:     opts.Retry,
+		policy:    opts.Policy,
+		minAge:    opts.MinAge,
+		lookalike: opts.Lookalike,
 	}
-}
-
-// calculateInputChecksum creates a simple checksum of input specs
-func (r *Resolver) calculateInputChecksum(specs []gomod.ModuleSpec) string {
-	var input string
-	for _, spec := range specs {
-		input += spec.Path + "@" + spec.Version + "|"
+	if r.retry.Attempts == 0 {
+		r.retry = retry.DefaultPolicy
 	}
-	// Simple checksum: just hash the string representation
-	return fmt.Sprintf("%x", len(input))
+	if r.queryTTL <= 0 {
+		r.queryTTL = DefaultQueryTTL
+	}
+	r.env = r.buildEnv(os.Environ(), opts)
+	r.settings = fmt.Sprintf("GOPROXY=%s\nGONOSUMDB=%s\nGOPRIVATE=%s\nGOFLAGS=%s\n",
+		opts.GoProxy, opts.GoNoSumDB, opts.GoPrivate, opts.GoFlags)
+	return r
 }
 
-// loadCache attempts to load resolution results from cache file
-func (r *Resolver) loadCache(specs []gomod.ModuleSpec) *ResolutionCache {
-	if !r.useCache {
-		log.Debug("Cache disabled, skipping cache load")
-		return nil
+// buildEnv derives the environment for go subprocesses from base, dropping
+// any inherited go configuration and pinning the module cache under workDir.
+func (r *Resolver) buildEnv(base []string, opts Options) []string {
+	goProxy := opts.GoProxy
+	if goProxy == "" {
+		goProxy = DefaultGoProxy
 	}
 
-	data, err := os.ReadFile(r.cacheFile)
-	if err != nil {
-		log.Debug("Cache file not found or unreadable: %v", err)
-		return nil
+	var env []string
+	for _, kv := range base {
+		name := kv
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			name = kv[:i]
+		}
+		if isStrippedGoVar(name) {
+			continue
+		}
+		env = append(env, kv)
 	}
 
-	var cache ResolutionCache
-	if err := json.Unmarshal(data, &cache); err != nil {
-		log.Error("Failed to parse cache file: %v", err)
-		return nil
-	}
+	return append(env,
+		"GOENV=off",
+		"GOWORK=off",
+		"GO111MODULE=on",
+		"GOPATH="+r.goPath,
+		"GOMODCACHE="+r.modCache,
+		"GOPROXY="+goProxy,
+		"GONOSUMDB="+opts.GoNoSumDB,
+		"GOPRIVATE="+opts.GoPrivate,
+		"GOFLAGS="+opts.GoFlags,
+	)
+}
 
-	// Verify input specs match
-	expectedChecksum := r.calculateInputChecksum(specs)
-	if cache.InputChecksum != expectedChecksum {
-		log.Info("Cache invalidated: input specs changed")
-		return nil
+func isStrippedGoVar(name string) bool {
+	for _, v := range strippedGoVars {
+		if name == v {
+			return true
+		}
 	}
+	return false
+}
 
-	log.Info("Loaded resolution cache from %s (%d modules)", r.cacheFile, len(cache.Modules))
-	return &cache
+// goCommand returns a go command that runs in dir with the isolated environment.
+func (r *Resolver) goCommand(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = r.env
+	return cmd
 }
 
-// saveCache saves resolution results to cache file
-func (r *Resolver) saveCache(specs []gomod.ModuleSpec, modules []gomod.Module) error {
-	if !r.useCache {
-		return nil
+// Cleanup removes the isolated module cache and GOPATH. The go command writes
+// the module cache read-only, so permissions are restored before removal.
+// Call it only after the resolved modules have been packed, since their
+// InfoFile, ModFile and ZipFile point into the module cache.
+func (r *Resolver) Cleanup() error {
+	for _, dir := range []string{r.modCache, r.goPath} {
+		if err := removeReadOnlyTree(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
 	}
+	return nil
+}
 
-	cache := ResolutionCache{
-		Version:       "1.0",
-		CachedAt:      time.Now(),
-		Modules:       modules,
-		InputSpecs:    specs,
-		InputChecksum: r.calculateInputChecksum(specs),
+func removeReadOnlyTree(dir string) error {
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return nil
 	}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			os.Chmod(path, info.Mode().Perm()|0200)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
 
-	data, err := json.MarshalIndent(cache, "", "  ")
-	if err != nil {
-		log.Error("Failed to marshal cache: %v", err)
-		return err
-	}
+// Failures returns the modules that failed to resolve or download during the
+// last call to ResolveDependencies.
+func (r *Resolver) Failures() []*ResolveError {
+	return r.failures
+}
 
-	if err := os.WriteFile(r.cacheFile, data, 0644); err != nil {
-		log.Error("Failed to write cache file: %v", err)
-		return err
+func (r *Resolver) recordFailure(path, version string, err error) {
+	rerr, ok := err.(*ResolveError)
+	if !ok {
+		rerr = &ResolveError{
+			Path:    path,
+			Version: version,
+			Kind:    KindUnknown,
+			Message: err.Error(),
+			Err:     err,
+		}
 	}
-
-	log.Info("Saved resolution cache to %s", r.cacheFile)
-	return nil
+	log.Error("Failed to resolve %s@%s (%s): %s", path, version, rerr.Kind, rerr.Message)
+	r.failures = append(r.failures, rerr)
 }
 
+// ResolveDependencies resolves specs and everything they depend on. Modules
+// that fail are skipped and reported by Failures; in strict mode the resolved
+// modules are still returned, together with a *FailureError.
 func (r *Resolver) ResolveDependencies(specs []gomod.ModuleSpec) ([]gomod.Module, error) {
-	// Try to load from cache first
-	if cache := r.loadCache(specs); cache != nil {
-		log.Info("Using cached resolution results (%d modules)", len(cache.Modules))
-		return cache.Modules, nil
+	r.loadCache()
+	r.failures = nil
+	r.graph = graph.New()
+	r.violations = nil
+	r.seenViolations = make(map[string]bool)
+	r.skipped = nil
+	blocked := make(map[string]bool)
+	heldBack := make(map[string]bool)
+
+	roots := make(map[string]bool)
+	for _, spec := range specs {
+		roots[spec.Path+"@"+spec.Version] = true
 	}
 
-	// Cache miss or disabled, perform full resolution
-	log.Info("Resolving dependencies (cache miss or disabled)")
-
 	// Track resolved modules by Path@Version to avoid duplicates
 	resolvedModules := make(map[string]gomod.Module)
 	var toProcess []gomod.ModuleSpec
 	processed := make(map[string]bool)
+	cacheHits := 0
 
 	// Start with provided specs
 	toProcess = append(toProcess, specs...)
@@ -154,45 +273,320 @@ func (r *Resolver) ResolveDependencies(specs []gomod.ModuleSpec) ([]gomod.Module
 		}
 		processed[key] = true
 
-		// Resolve this module and its dependencies
-		log.Info("Resolve [queue %v resolved %v] %v", len(toProcess), len(resolvedModules), key)
-		resolvedMods, err := r.resolveModule(spec)
+		// Queries are pinned to a version outside the minimum age first, so
+		// that they never select a release that is too new
+		pinned, err := r.applyCooldown(spec)
 		if err != nil {
-			log.Error("Failed to resolve %s@%s: %v", spec.Path, spec.Version, err)
-			//return nil, fmt.Errorf("failed to resolve %s@%s: %w", spec.Path, spec.Version, err)
+			if err == errNoFallback {
+				err = tooNewError(spec.Path, spec.Version, err)
+			}
+			r.recordFailure(spec.Path, spec.Version, err)
+			continue
+		}
+		if pinned.Version != spec.Version {
+			pinnedKey := pinned.Path + "@" + pinned.Version
+			roots[pinnedKey] = roots[pinnedKey] || roots[key]
+			spec, key = pinned, pinnedKey
+			if processed[key] {
+				continue
+			}
+			processed[key] = true
+		}
+
+		// A blocked module is not resolved, so its requirements are only
+		// mirrored if something else needs them. Queries are checked once
+		// they are resolved to a version, below
+		if gomod.IsCanonicalVersion(spec.Version) && r.checkPolicy(policy.Subject{Path: spec.Path, Version: spec.Version}) {
+			log.Info("Blocked by policy: %s", key)
+			blocked[key] = true
+			continue
+		}
+
+		// Resolve this module and its dependencies, unless an earlier run
+		// already did so under the same settings
+		entry := r.lookupCache(spec)
+		if entry != nil {
+			cacheHits++
+			log.Debug("Cache hit [queue %v resolved %v] %v", len(toProcess), len(resolvedModules), key)
+		} else {
+			log.Info("Resolve [queue %v resolved %v] %v", len(toProcess), len(resolvedModules), key)
+			var err error
+			entry, err = r.resolveEntryWithRetry(spec)
+			if err != nil {
+				r.recordFailure(spec.Path, spec.Version, err)
+				continue
+			}
+			r.storeCache(spec, entry)
+		}
+
+		// A query selecting a blocked version has its requirements dropped
+		// as well
+		if !gomod.IsCanonicalVersion(spec.Version) && entry.Version != "" {
+			resolvedKey := entry.Path + "@" + entry.Version
+			if r.checkPolicy(policy.Subject{Path: entry.Path, Version: entry.Version}) {
+				log.Info("Blocked by policy: %s (%s)", resolvedKey, key)
+				blocked[resolvedKey] = true
+				continue
+			}
 		}
 
+		// A version within the minimum age is dropped before its
+		// requirements are queued, so that they are not mirrored for it
+		if entry.Version != "" && r.minAge > 0 {
+			if t := r.releaseTime(entry.Path, entry.Version); r.tooNew(t) {
+				r.recordSkipped(SkippedVersion{Path: entry.Path, Version: entry.Version, Time: t})
+				heldBack[entry.Path+"@"+entry.Version] = true
+				if roots[key] {
+					r.recordFailure(spec.Path, spec.Version, tooNewError(entry.Path, entry.Version, errTooNew))
+				}
+				continue
+			}
+		}
+		r.recordEdges(entry, roots[key])
+
 		// Add resolved modules to our map
-		for _, mod := range resolvedMods {
-			modKey := mod.Path + "@" + mod.Version
-			resolvedModules[modKey] = mod
+		for _, dep := range entry.BuildList {
+			modKey := dep.Path + "@" + dep.Version
+			if _, ok := resolvedModules[modKey]; !ok {
+				resolvedModules[modKey] = gomod.Module{Path: dep.Path, Version: dep.Version}
+			}
 
 			// If this is a new module we haven't seen before, queue it for resolution
 			if !processed[modKey] {
-				log.Info("  -> %v", modKey)
-				toProcess = append(toProcess, gomod.ModuleSpec{
-					Path:    mod.Path,
-					Version: mod.Version,
-				})
+				log.Debug("  -> %v", modKey)
+				toProcess = append(toProcess, dep)
 			}
 		}
 	}
+	log.Info("Resolution finished: %d modules, %d specs served from cache", len(resolvedModules), cacheHits)
 
 	// Convert map back to slice
 	var result []gomod.Module
-	for _, mod := range resolvedModules {
+	for key, mod := range resolvedModules {
+		if blocked[key] || heldBack[key] {
+			continue
+		}
 		result = append(result, mod)
 	}
 
 	// Save to cache for next run
-	if err := r.saveCache(specs, result); err != nil {
+	if err := r.saveCache(); err != nil {
 		log.Error("Failed to save cache: %v", err)
 		// Don't fail the whole operation if cache save fails
 	}
+	if err := r.graph.Save(GraphFile(r.workDir)); err != nil {
+		log.Error("Failed to save resolution graph: %v", err)
+	}
 
+	// Cached specs were never downloaded in this run, so make sure every
+	// module has its files in the module cache before handing them to packing
+	result = r.locateFiles(result)
+	result = r.applyMinAge(result)
+	result = r.applyPolicy(result)
+	r.checkLookalikes(result)
+
+	if r.strict && len(r.failures) > 0 {
+		return result, &FailureError{Failures: r.failures}
+	}
 	return result, nil
 }
 
+// GraphFile returns where the requirement graph of the last resolution run in
+// workDir is stored.
+func GraphFile(workDir string) string {
+	return filepath.Join(workDir, "resolution-graph.json")
+}
+
+// Graph returns the requirement graph discovered by the last call to
+// ResolveDependencies.
+func (r *Resolver) Graph() *graph.Graph {
+	return r.graph
+}
+
+// recordEdges adds the resolved module of entry and its direct requirements
+// to the graph.
+func (r *Resolver) recordEdges(entry *CacheEntry, isRoot bool) {
+	if entry.Version == "" {
+		return
+	}
+	node := entry.Path + "@" + entry.Version
+	if isRoot {
+		r.graph.AddRoot(node)
+	}
+	for _, req := range entry.Requires {
+		r.graph.AddEdge(node, req.Path+"@"+req.Version)
+	}
+}
+
+// resolveEntryWithRetry is resolveEntry retried according to the retry
+// policy. Only network errors are retried; a missing module or a broken go.mod
+// will not get better on a second attempt.
+func (r *Resolver) resolveEntryWithRetry(spec gomod.ModuleSpec) (*CacheEntry, error) {
+	var entry *CacheEntry
+	err := r.retry.Do("Resolve "+spec.Path+"@"+spec.Version, func() error {
+		var err error
+		entry, err = r.resolveEntry(spec)
+		var rerr *ResolveError
+		if err != nil && !(errors.As(err, &rerr) && rerr.Kind == KindNetwork) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+	return entry, err
+}
+
+// resolveEntry resolves spec with the go command and records the outcome as a
+// cache entry.
+func (r *Resolver) resolveEntry(spec gomod.ModuleSpec) (*CacheEntry, error) {
+	mods, err := r.resolveModule(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &CacheEntry{
+		Path:     spec.Path,
+		Query:    spec.Version,
+		CachedAt: time.Now(),
+	}
+	for _, mod := range mods {
+		entry.BuildList = append(entry.BuildList, gomod.ModuleSpec{Path: mod.Path, Version: mod.Version})
+		if mod.Path != spec.Path {
+			continue
+		}
+		entry.Version = mod.Version
+		if mod.ModFile == "" {
+			continue
+		}
+		data, err := os.ReadFile(mod.ModFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read go.mod of %s@%s: %w", mod.Path, mod.Version, err)
+		}
+		if entry.Requires, err = gomod.ParseRequires(string(data)); err != nil {
+			return nil, &ResolveError{
+				Path:    mod.Path,
+				Version: mod.Version,
+				Kind:    KindInvalidGoMod,
+				Message: err.Error(),
+				Err:     err,
+			}
+		}
+	}
+	return entry, nil
+}
+
+// locateFiles fills in the module cache file paths of mods, downloading the
+// modules whose zip is not in the module cache yet. Modules that cannot be
+// downloaded are dropped from the result.
+func (r *Resolver) locateFiles(mods []gomod.Module) []gomod.Module {
+	var missing []string
+	for i := range mods {
+		r.setCacheFiles(&mods[i])
+		if _, err := os.Stat(mods[i].ZipFile); err != nil {
+			missing = append(missing, mods[i].Path+"@"+mods[i].Version)
+		}
+	}
+	if len(missing) == 0 {
+		return mods
+	}
+
+	log.Info("Downloading %d modules not present in the module cache", len(missing))
+	failed := make(map[string]string)
+	pending := missing
+	r.retry.Do(fmt.Sprintf("Download of %d modules", len(missing)), func() error {
+		// Only downloads that failed with a network error are attempted again
+		var retryable []string
+		for _, key := range pending {
+			delete(failed, key)
+		}
+		const batchSize = 100
+		for start := 0; start < len(pending); start += batchSize {
+			end := start + batchSize
+			if end > len(pending) {
+				end = len(pending)
+			}
+			for key, msg := range r.downloadModules(pending[start:end]) {
+				failed[key] = msg
+				if classifyGoError(msg) == KindNetwork {
+					retryable = append(retryable, key)
+				}
+			}
+		}
+		pending = retryable
+		if len(pending) > 0 {
+			return fmt.Errorf("%d modules failed with network errors", len(pending))
+		}
+		return nil
+	})
+
+	result := mods[:0]
+	for _, mod := range mods {
+		if msg, ok := failed[mod.Path+"@"+mod.Version]; ok {
+			r.recordFailure(mod.Path, mod.Version, newResolveError(mod.Path, mod.Version, "", errors.New(msg)))
+			continue
+		}
+		result = append(result, mod)
+	}
+	return result
+}
+
+// setCacheFiles points the file fields of mod at their location in the
+// isolated module cache.
+func (r *Resolver) setCacheFiles(mod *gomod.Module) {
+	escPath, err := gomod.EscapePath(mod.Path)
+	if err != nil {
+		return
+	}
+	escVersion, err := gomod.EscapeVersion(mod.Version)
+	if err != nil {
+		return
+	}
+	base := filepath.Join(r.modCache, "cache", "download", filepath.FromSlash(escPath), "@v", escVersion)
+	mod.InfoFile = base + ".info"
+	mod.ModFile = base + ".mod"
+	mod.ZipFile = base + ".zip"
+	mod.Dir = filepath.Join(r.modCache, filepath.FromSlash(escPath)+"@"+escVersion)
+}
+
+// downloadModules runs go mod download for the given path@version keys and
+// returns the error message of every key that failed.
+func (r *Resolver) downloadModules(keys []string) map[string]string {
+	args := append([]string{"mod", "download", "-json"}, keys...)
+	cmd := r.goCommand(r.workDir, args...)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		log.Debug("go mod download -json completed with errors: %v", err)
+	}
+
+	done := make(map[string]bool)
+	failed := make(map[string]string)
+	decoder := json.NewDecoder(&stdout)
+	for decoder.More() {
+		var dlInfo struct {
+			Path    string
+			Version string
+			Zip     string
+			Error   string
+		}
+		if err := decoder.Decode(&dlInfo); err != nil {
+			break
+		}
+		if dlInfo.Error == "" && dlInfo.Zip != "" {
+			done[dlInfo.Path+"@"+dlInfo.Version] = true
+		} else if dlInfo.Error != "" {
+			failed[dlInfo.Path+"@"+dlInfo.Version] = dlInfo.Error
+		}
+	}
+
+	for _, key := range keys {
+		if _, ok := failed[key]; !ok && !done[key] {
+			failed[key] = "module not reported by go mod download"
+		}
+	}
+	return failed
+}
+
 func (r *Resolver) resolveModule(spec gomod.ModuleSpec) ([]gomod.Module, error) {
 	// Create a temporary directory for go mod resolution
 	// Use a simple naming scheme since multiple versions will be handled in sequence
@@ -216,29 +610,26 @@ func (r *Resolver) resolveModule(spec gomod.ModuleSpec) ([]gomod.Module, error)
 	}
 
 	log.Debug("Running 'go get -d %s' in %s", getSpec, tempDir)
-	getCmd := exec.Command("go", "get", "-d", getSpec)
-	getCmd.Dir = tempDir
+	getCmd := r.goCommand(tempDir, "get", "-d", getSpec)
 
 	var getStderr bytes.Buffer
 	getCmd.Stderr = &getStderr
 
 	if err := getCmd.Run(); err != nil {
-		log.Error("go get -d %s stderr: %s", getSpec, getStderr.String())
-		return nil, fmt.Errorf("go get -d %s failed: %w", getSpec, err)
+		log.Debug("go get -d %s stderr: %s", getSpec, getStderr.String())
+		return nil, newResolveError(spec.Path, spec.Version, getStderr.String(), fmt.Errorf("go get -d %s failed: %w", getSpec, err))
 	}
 
 	// Download ALL modules (including transitive dependencies)
 	log.Debug("Running 'go mod download' to download all modules")
-	downloadAllCmd := exec.Command("go", "mod", "download")
-	downloadAllCmd.Dir = tempDir
+	downloadAllCmd := r.goCommand(tempDir, "mod", "download")
 	if err := downloadAllCmd.Run(); err != nil {
 		log.Debug("go mod download completed with some warnings")
 	}
 
 	// Now get paths using go mod download -json
 	log.Debug("Running 'go mod download -json' to get module paths")
-	downloadJsonCmd := exec.Command("go", "mod", "download", "-json")
-	downloadJsonCmd.Dir = tempDir
+	downloadJsonCmd := r.goCommand(tempDir, "mod", "download", "-json")
 
 	var dlStdout bytes.Buffer
 	downloadJsonCmd.Stdout = &dlStdout
@@ -289,16 +680,15 @@ func (r *Resolver) resolveModule(spec gomod.ModuleSpec) ([]gomod.Module, error)
 
 	// Run go list -m -json all to get all modules with versions
 	log.Debug("Running 'go list -m -json all' to resolve dependencies")
-	listCmd := exec.Command("go", "list", "-m", "-json", "all")
-	listCmd.Dir = tempDir
+	listCmd := r.goCommand(tempDir, "list", "-m", "-json", "all")
 
 	var stdout, stderr bytes.Buffer
 	listCmd.Stdout = &stdout
 	listCmd.Stderr = &stderr
 
 	if err := listCmd.Run(); err != nil {
-		log.Error("go list output: %s", stderr.String())
-		return nil, fmt.Errorf("go list -m -json failed: %w", err)
+		log.Debug("go list output: %s", stderr.String())
+		return nil, newResolveError(spec.Path, spec.Version, stderr.String(), fmt.Errorf("go list -m -json failed: %w", err))
 	}
 
 	// Parse the go list output
@@ -0,0 +1,314 @@
+package resolver
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/policy"
+)
+
+func TestBuildEnv_IsolatesGoConfiguration(t *testing.T) {
+	workDir := t.TempDir()
+	r := NewResolverWithOptions(workDir, Options{GoPrivate: "corp.example.com"})
+
+	base := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/user",
+		"GOPROXY=https://personal.example.com",
+		"GOFLAGS=-mod=vendor",
+		"GOMODCACHE=/home/user/go/pkg/mod",
+		"GONOSUMDB=example.com",
+	}
+	env := r.buildEnv(base, Options{GoPrivate: "corp.example.com"})
+
+	got := make(map[string][]string)
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		got[parts[0]] = append(got[parts[0]], parts[1])
+	}
+
+	want := map[string]string{
+		"PATH":       "/usr/bin",
+		"HOME":       "/home/user",
+		"GOPROXY":    DefaultGoProxy,
+		"GOFLAGS":    "",
+		"GOMODCACHE": filepath.Join(workDir, "modcache"),
+		"GONOSUMDB":  "",
+		"GOPRIVATE":  "corp.example.com",
+		"GOENV":      "off",
+	}
+	for name, value := range want {
+		values := got[name]
+		if len(values) != 1 {
+			t.Errorf("%s set %d times: %v", name, len(values), values)
+			continue
+		}
+		if values[0] != value {
+			t.Errorf("%s = %q, want %q", name, values[0], value)
+		}
+	}
+}
+
+func TestCacheKey_DependsOnSettings(t *testing.T) {
+	workDir := t.TempDir()
+	spec := gomod.ModuleSpec{Path: "github.com/gin-gonic/gin", Version: "v1.9.1"}
+
+	a := NewResolverWithOptions(workDir, Options{UseCache: true})
+	b := NewResolverWithOptions(workDir, Options{UseCache: true})
+	c := NewResolverWithOptions(workDir, Options{UseCache: true, GoFlags: "-insecure"})
+	d := NewResolverWithOptions(workDir, Options{UseCache: true, GoProxy: DefaultGoProxy})
+
+	if a.cacheKey(spec) != b.cacheKey(spec) {
+		t.Error("Same spec and settings produced different keys")
+	}
+	if a.cacheKey(spec) == c.cacheKey(spec) {
+		t.Error("Different settings produced the same key")
+	}
+	if a.cacheKey(spec) != d.cacheKey(spec) {
+		t.Error("Empty and explicit default GOPROXY produced different keys")
+	}
+	other := gomod.ModuleSpec{Path: "github.com/gin-gonic/gin", Version: "v1.9.2"}
+	if a.cacheKey(spec) == a.cacheKey(other) {
+		t.Error("Same-length versions produced the same key")
+	}
+}
+
+func TestCache_RoundTripAndQueryTTL(t *testing.T) {
+	workDir := t.TempDir()
+	r := NewResolverWithOptions(workDir, Options{UseCache: true, QueryTTL: time.Hour})
+	r.loadCache()
+
+	pinned := gomod.ModuleSpec{Path: "github.com/sirupsen/logrus", Version: "v1.9.3"}
+	latest := gomod.ModuleSpec{Path: "github.com/golang/protobuf"}
+	old := time.Now().Add(-2 * time.Hour)
+
+	r.storeCache(pinned, &CacheEntry{Path: pinned.Path, Query: pinned.Version, Version: "v1.9.3", CachedAt: old})
+	r.storeCache(latest, &CacheEntry{Path: latest.Path, Version: "v1.5.3", CachedAt: old})
+	if err := r.saveCache(); err != nil {
+		t.Fatalf("saveCache failed: %v", err)
+	}
+
+	reloaded := NewResolverWithOptions(workDir, Options{UseCache: true, QueryTTL: time.Hour})
+	reloaded.loadCache()
+	if reloaded.lookupCache(pinned) == nil {
+		t.Error("Entry for canonical version should never expire")
+	}
+	if reloaded.lookupCache(latest) != nil {
+		t.Error("Entry for query spec should expire after the TTL")
+	}
+}
+
+func TestClassifyGoError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   ErrorKind
+	}{
+		{"go: example.com/a@v1.0.0: reading https://proxy.golang.org/example.com/a/@v/v1.0.0.mod: 404 Not Found", KindNotFound},
+		{"go: example.com/a@v9.9.9: invalid version: unknown revision v9.9.9", KindNotFound},
+		{"verifying example.com/a@v1.0.0: checksum mismatch\n\tdownloaded: h1:abc\n\tgo.sum: h1:def\n\nSECURITY ERROR", KindChecksumMismatch},
+		{"go: example.com/a@v1.0.0: parsing go.mod:\n\tmodule declares its path as: example.com/b", KindInvalidGoMod},
+		{"dial tcp: lookup proxy.golang.org: no such host", KindNetwork},
+		{"reading https://proxy.golang.org/x/@v/list: 502 Bad Gateway", KindNetwork},
+		{"go: example.com/a@v1.0.0: git ls-remote -q origin: exit status 128:\n\tremote: Repository not found.", KindNotFound},
+		{"go: example.com/a@v1.0.0: module lookup disabled by GOPROXY=off", KindNotFound},
+		{"something unexpected", KindUnknown},
+		{"open /tmp/work/go.mod: no such file or directory", KindUnknown},
+		{"exec: \"go\": executable file not found in $PATH", KindUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := classifyGoError(tt.output); got != tt.want {
+			t.Errorf("classifyGoError(%q) = %s, want %s", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestSatisfiesQuery(t *testing.T) {
+	tests := []struct {
+		query, version string
+		want           bool
+	}{
+		{"latest", "v1.2.3", true},
+		{"v1", "v1.9.0", true},
+		{"v1", "v10.0.0", false},
+		{"v1.2", "v1.2.7", true},
+		{"v1.2", "v1.3.0", false},
+		{"<v1.5.0", "v1.4.9", true},
+		{"<v1.5.0", "v1.5.0", false},
+		{">=v1.2.0", "v1.2.0", true},
+		{">=v1.2.0", "v1.1.9", false},
+		{"master", "v1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := satisfiesQuery(tt.query, tt.version); got != tt.want {
+			t.Errorf("satisfiesQuery(%q, %q) = %v, want %v", tt.query, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestPickFallback(t *testing.T) {
+	now := time.Now()
+	released := map[string]time.Time{
+		"v1.0.0":      now.Add(-30 * 24 * time.Hour),
+		"v1.1.0":      now.Add(-10 * 24 * time.Hour),
+		"v1.2.0-rc.1": now.Add(-5 * 24 * time.Hour),
+		"v1.2.0":      now.Add(-48 * time.Hour),
+		"v1.3.0":      now.Add(-time.Hour),
+	}
+	versions := []string{"v1.0.0", "v1.1.0", "v1.2.0-rc.1", "v1.2.0", "v1.3.0"}
+	timeOf := func(v string) (time.Time, error) { return released[v], nil }
+	r := NewResolverWithOptions(t.TempDir(), Options{MinAge: 72 * time.Hour})
+
+	tests := []struct {
+		query, selected, want string
+	}{
+		{"latest", "v1.3.0", "v1.1.0"}, // v1.2.0 is too new, v1.2.0-rc.1 is a prerelease
+		{">=v1.1.0", "v1.3.0", "v1.1.0"},
+		{"v1.0", "v1.0.0", ""},
+		{">v1.1.0", "v1.3.0", ""},
+	}
+	for _, tt := range tests {
+		got, err := pickFallback(tt.query, tt.selected, versions, timeOf, r.tooNew)
+		if err != nil {
+			t.Fatalf("pickFallback(%q) failed: %v", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("pickFallback(%q, %s) = %q, want %q", tt.query, tt.selected, got, tt.want)
+		}
+	}
+}
+
+// writeProxyModule adds path@version, released at released and importing
+// the given path@version modules, to a file:// module proxy in dir.
+func writeProxyModule(t *testing.T, dir, path, version string, released time.Time, requires ...string) {
+	t.Helper()
+	atV := filepath.Join(dir, filepath.FromSlash(path), "@v")
+	if err := os.MkdirAll(atV, 0755); err != nil {
+		t.Fatal(err)
+	}
+	goMod := "module " + path + "\n\ngo 1.21\n"
+	lib := "package lib\n"
+	for _, req := range requires {
+		goMod += "\nrequire " + strings.Replace(req, "@", " ", 1) + "\n"
+		lib += "\nimport _ \"" + strings.SplitN(req, "@", 2)[0] + "\"\n"
+	}
+	info := fmt.Sprintf(`{"Version":%q,"Time":%q}`, version, released.UTC().Format(time.RFC3339))
+	files := map[string]string{
+		"list":            version + "\n",
+		version + ".info": info,
+		version + ".mod":  goMod,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(atV, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Create(filepath.Join(atV, version+".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	prefix := path + "@" + version + "/"
+	for name, data := range map[string]string{"go.mod": goMod, "lib.go": lib} {
+		w, err := zw.Create(prefix + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveDependencies_MinAge(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	proxy := t.TempDir()
+	old, recent := time.Now().Add(-30*24*time.Hour), time.Now().Add(-time.Hour)
+	writeProxyModule(t, proxy, "example.com/old", "v1.0.0", old)
+	writeProxyModule(t, proxy, "example.com/dep", "v1.0.0", old)
+	writeProxyModule(t, proxy, "example.com/new", "v1.0.0", recent, "example.com/dep@v1.0.0")
+	writeProxyModule(t, proxy, "example.com/fresh", "v1.0.0", recent)
+
+	r := NewResolverWithOptions(t.TempDir(), Options{
+		GoProxy:   "file://" + filepath.ToSlash(proxy),
+		GoNoSumDB: "example.com",
+		MinAge:    72 * time.Hour,
+		Strict:    true,
+	})
+	mods, err := r.ResolveDependencies([]gomod.ModuleSpec{
+		{Path: "example.com/old", Version: "v1.0.0"},
+		{Path: "example.com/new", Version: "v1.0.0"},
+		{Path: "example.com/fresh", Version: "latest"},
+	})
+
+	// The requirements of a version within the minimum age are not mirrored
+	if len(mods) != 1 || mods[0].Path != "example.com/old" {
+		t.Errorf("Resolved %v, want only example.com/old", mods)
+	}
+	// Requested versions that are held back fail strict mode
+	var failure *FailureError
+	if !errors.As(err, &failure) {
+		t.Fatalf("ResolveDependencies returned %v, want a *FailureError", err)
+	}
+	got := make(map[string]ErrorKind)
+	for _, f := range failure.Failures {
+		got[f.Path] = f.Kind
+	}
+	if len(got) != 2 || got["example.com/new"] != KindTooNew || got["example.com/fresh"] != KindTooNew {
+		t.Errorf("Failures = %v, want example.com/new and example.com/fresh too new", got)
+	}
+	if len(r.Skipped()) != 2 {
+		t.Errorf("Skipped = %+v, want 2 versions", r.Skipped())
+	}
+}
+
+func TestResolveDependencies_PolicyBlocksQuery(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	proxy := t.TempDir()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	writeProxyModule(t, proxy, "example.com/dep", "v1.0.0", old)
+	writeProxyModule(t, proxy, "example.com/banned", "v1.0.0", old, "example.com/dep@v1.0.0")
+
+	file := filepath.Join(t.TempDir(), "policy.json")
+	rules := `{"rules": [{"name": "banned", "action": "deny", "paths": ["example.com/banned"]}]}`
+	if err := os.WriteFile(file, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := policy.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewResolverWithOptions(t.TempDir(), Options{
+		GoProxy:   "file://" + filepath.ToSlash(proxy),
+		GoNoSumDB: "example.com",
+		Policy:    p,
+	})
+	mods, err := r.ResolveDependencies([]gomod.ModuleSpec{{Path: "example.com/banned", Version: "latest"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The version a query selects is checked before its requirements are queued
+	if len(mods) != 0 {
+		t.Errorf("Resolved %v, want nothing", mods)
+	}
+	if v := r.Violations(); len(v) != 1 || v[0].Path != "example.com/banned" || v[0].Version != "v1.0.0" {
+		t.Errorf("Violations = %v, want example.com/banned@v1.0.0", v)
+	}
+}
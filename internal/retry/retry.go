@@ -0,0 +1,125 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/log"
+)
+
+// Policy describes how often and how patiently an operation is retried.
+// Delays grow exponentially from BaseDelay up to MaxDelay, and each delay is
+// randomized by up to Jitter (a fraction of the delay) so that concurrent
+// workers do not hammer an upstream in lockstep.
+type Policy struct {
+	Attempts  int // total attempts, including the first; values below 1 mean 1
+	BaseDelay time.Duration
+	MaxDelay  time.Duration // 0 for no limit
+	Jitter    float64
+}
+
+// DefaultPolicy is used when no retry settings are configured.
+var DefaultPolicy = Policy{
+	Attempts:  3,
+	BaseDelay: time.Second,
+	MaxDelay:  30 * time.Second,
+	Jitter:    0.2,
+}
+
+// sleep is replaced in tests.
+var sleep = time.Sleep
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying. Do returns the wrapped error
+// immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Delay returns the wait before retry number attempt (starting at 1).
+func (p Policy) Delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// Do runs fn until it succeeds, returns a permanent error or the attempts are
+// used up. The last error is returned with permanent wrappers removed.
+func (p Policy) Do(op string, fn func() error) error {
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if attempt == attempts {
+			break
+		}
+		delay := p.Delay(attempt)
+		log.Warn("%s failed (attempt %d/%d), retrying in %v: %v", op, attempt, attempts, delay.Round(time.Millisecond), err)
+		sleep(delay)
+	}
+	return err
+}
+
+// StatusError is an unexpected HTTP response from an upstream.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// IsTransientStatus reports whether an HTTP status code is worth retrying:
+// server errors, 408 Request Timeout and 429 Too Many Requests. Every other
+// status, notably 404 Not Found and 410 Gone, is permanent.
+func IsTransientStatus(code int) bool {
+	return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// ClassifyHTTP wraps err as permanent when it is a StatusError with a
+// permanent status code. Transport errors such as timeouts stay retryable.
+func ClassifyHTTP(err error) error {
+	var se *StatusError
+	if errors.As(err, &se) && !IsTransientStatus(se.StatusCode) {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func noSleep(t *testing.T) *[]time.Duration {
+	var delays []time.Duration
+	orig := sleep
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	t.Cleanup(func() { sleep = orig })
+	return &delays
+}
+
+func TestDo_RetriesTransientErrors(t *testing.T) {
+	delays := noSleep(t)
+	p := Policy{Attempts: 4, BaseDelay: 10 * time.Millisecond, MaxDelay: 25 * time.Millisecond}
+
+	calls := 0
+	err := p.Do("fetch", func() error {
+		calls++
+		if calls < 4 {
+			return errors.New("timeout")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("Got %d calls, want 4", calls)
+	}
+
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond}
+	if len(*delays) != len(want) {
+		t.Fatalf("Got delays %v, want %v", *delays, want)
+	}
+	for i := range want {
+		if (*delays)[i] != want[i] {
+			t.Errorf("Delay %d: got %v, want %v", i, (*delays)[i], want[i])
+		}
+	}
+}
+
+func TestDo_StopsOnPermanentError(t *testing.T) {
+	noSleep(t)
+	p := Policy{Attempts: 5, BaseDelay: time.Millisecond}
+
+	notFound := &StatusError{URL: "https://proxy.example.com/x/@v/list", StatusCode: 404}
+	calls := 0
+	err := p.Do("fetch", func() error {
+		calls++
+		return ClassifyHTTP(notFound)
+	})
+	if calls != 1 {
+		t.Errorf("Got %d calls, want 1", calls)
+	}
+	if err != notFound {
+		t.Errorf("Got error %v, want the unwrapped status error", err)
+	}
+}
+
+func TestClassifyHTTP(t *testing.T) {
+	tests := []struct {
+		code      int
+		permanent bool
+	}{
+		{404, true},
+		{410, true},
+		{400, true},
+		{408, false},
+		{429, false},
+		{500, false},
+		{503, false},
+	}
+
+	for _, tt := range tests {
+		err := ClassifyHTTP(&StatusError{StatusCode: tt.code})
+		if IsPermanent(err) != tt.permanent {
+			t.Errorf("Status %d: permanent = %v, want %v", tt.code, IsPermanent(err), tt.permanent)
+		}
+	}
+}
+
+func TestDelay_NoMaxDelay(t *testing.T) {
+	p := Policy{BaseDelay: time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second} {
+		if got := p.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+	if got := p.Delay(100); got <= 0 {
+		t.Errorf("Delay(100) = %v, want a positive delay", got)
+	}
+}
@@ -0,0 +1,127 @@
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type cdxDocument struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	License cdxLicenseID `json:"license"`
+}
+
+type cdxLicenseID struct {
+	ID string `json:"id"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteCycloneDX writes the BOM as a CycloneDX 1.5 JSON document. The
+// SHA-256 hash is that of the zip file; the h1 hashes go.sum records are
+// kept as properties.
+func (b *BOM) WriteCycloneDX(w io.Writer) error {
+	rootRef := "modules.txt"
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: b.Timestamp.Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{
+				{Type: "application", Name: ToolName},
+			}},
+			Component: cdxComponent{Type: "application", BOMRef: rootRef, Name: b.Name},
+		},
+		Components:   []cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+
+	purls := make(map[string]string, len(b.Components))
+	for _, c := range b.Components {
+		purls[c.Key()] = c.PURL()
+	}
+
+	rootDeps := []string{}
+	for _, r := range b.Roots {
+		rootDeps = append(rootDeps, purls[r])
+	}
+	doc.Dependencies = append(doc.Dependencies, cdxDependency{Ref: rootRef, DependsOn: rootDeps})
+
+	for _, c := range b.Components {
+		comp := cdxComponent{
+			Type:    "library",
+			BOMRef:  purls[c.Key()],
+			Name:    c.Path,
+			Version: c.Version,
+			PURL:    purls[c.Key()],
+		}
+		if c.ZipSHA256 != "" {
+			comp.Hashes = append(comp.Hashes, cdxHash{Alg: "SHA-256", Content: c.ZipSHA256})
+		}
+		if c.Hash != "" {
+			comp.Properties = append(comp.Properties, cdxProperty{Name: "golang:h1", Value: c.Hash})
+		}
+		if c.GoModHash != "" {
+			comp.Properties = append(comp.Properties, cdxProperty{Name: "golang:go.mod:h1", Value: c.GoModHash})
+		}
+		for _, id := range c.Licenses {
+			comp.Licenses = append(comp.Licenses, cdxLicense{License: cdxLicenseID{ID: id}})
+		}
+		doc.Components = append(doc.Components, comp)
+
+		deps := []string{}
+		for _, d := range b.Dependencies[c.Key()] {
+			deps = append(deps, purls[d])
+		}
+		doc.Dependencies = append(doc.Dependencies, cdxDependency{Ref: comp.BOMRef, DependsOn: deps})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
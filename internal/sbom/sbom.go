@@ -0,0 +1,114 @@
+package sbom
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/graph"
+)
+
+// ToolName identifies this tool as the SBOM creator.
+const ToolName = "go-mod-clone"
+
+// Component is one module version included in the SBOM.
+type Component struct {
+	Path      string
+	Version   string
+	Hash      string   // h1 hash of the module zip
+	ZipSHA256 string   // hex SHA-256 of the module zip file
+	GoModHash string   // h1 hash of the go.mod file
+	Licenses  []string // SPDX identifiers, empty if unknown
+}
+
+// Key returns the "path@version" form used by the resolution graph.
+func (c Component) Key() string {
+	return c.Path + "@" + c.Version
+}
+
+// PURL returns the package URL of the module version.
+func (c Component) PURL() string {
+	segments := strings.Split(c.Path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return "pkg:golang/" + strings.Join(segments, "/") + "@" + url.PathEscape(c.Version)
+}
+
+// BOM is the bill of materials of one prefill run: the modules it resolved
+// and packed, and the requirement edges between them.
+type BOM struct {
+	Name         string
+	Timestamp    time.Time
+	Components   []Component
+	Roots        []string            // component keys listed in modules.txt
+	Dependencies map[string][]string // component key -> required component keys
+}
+
+// New builds a BOM from the packed components and the resolution graph.
+// Graph edges point at the versions modules declare; an edge to a version
+// that was not packed, because MVS selected a higher one, leads to the
+// highest packed version of that module instead. Modules without a packed
+// version are left out.
+func New(name string, components []Component, g *graph.Graph) *BOM {
+	b := &BOM{
+		Name:         name,
+		Timestamp:    time.Now().UTC(),
+		Components:   append([]Component(nil), components...),
+		Dependencies: make(map[string][]string),
+	}
+	sort.Slice(b.Components, func(i, j int) bool { return b.Components[i].Key() < b.Components[j].Key() })
+
+	included := make(map[string]bool, len(components))
+	selected := make(map[string]string) // module path -> highest packed version
+	for _, c := range components {
+		included[c.Key()] = true
+		if v, ok := selected[c.Path]; !ok || gomod.CompareVersions(c.Version, v) > 0 {
+			selected[c.Path] = c.Version
+		}
+	}
+	if g == nil {
+		return b
+	}
+
+	for _, r := range g.Roots {
+		if included[r] {
+			b.Roots = append(b.Roots, r)
+		}
+	}
+	sort.Strings(b.Roots)
+	for _, c := range b.Components {
+		var deps []string
+		seen := make(map[string]bool)
+		for _, child := range g.Children(c.Key()) {
+			if !included[child] {
+				path, _, _ := strings.Cut(child, "@")
+				v, ok := selected[path]
+				if !ok {
+					continue
+				}
+				child = path + "@" + v
+			}
+			if child != c.Key() && !seen[child] {
+				seen[child] = true
+				deps = append(deps, child)
+			}
+		}
+		sort.Strings(deps)
+		b.Dependencies[c.Key()] = deps
+	}
+	return b
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
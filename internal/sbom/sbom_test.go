@@ -0,0 +1,112 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/example/go-mod-clone/internal/graph"
+)
+
+const testZipSHA256 = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+func testBOM() *BOM {
+	g := graph.New()
+	g.AddRoot("example.com/a@v1.0.0")
+	g.AddEdge("example.com/a@v1.0.0", "example.com/b@v1.0.0")
+	g.AddEdge("example.com/a@v1.0.0", "example.com/pruned@v0.1.0")
+
+	return New("modules.txt", []Component{
+		{Path: "example.com/b", Version: "v1.0.0", Hash: "h1:CrwycVLIkQiVbiQpiDQUWb331gUL92I6KZyAMNO8sjE=", ZipSHA256: testZipSHA256, Licenses: []string{"MIT"}},
+		{Path: "example.com/a", Version: "v1.0.0", Licenses: []string{"Apache-2.0", "MIT"}},
+	}, g)
+}
+
+func TestNew_RestrictsGraphToComponents(t *testing.T) {
+	b := testBOM()
+	deps := b.Dependencies["example.com/a@v1.0.0"]
+	if len(deps) != 1 || deps[0] != "example.com/b@v1.0.0" {
+		t.Errorf("Dependencies of a = %v, want only b", deps)
+	}
+	if len(b.Roots) != 1 {
+		t.Errorf("Roots = %v", b.Roots)
+	}
+}
+
+func TestNew_MapsDeclaredToSelectedVersions(t *testing.T) {
+	g := graph.New()
+	g.AddRoot("example.com/a@v1.0.0")
+	g.AddRoot("example.com/c@v1.0.0")
+	g.AddEdge("example.com/a@v1.0.0", "example.com/b@v1.0.0")
+	g.AddEdge("example.com/c@v1.0.0", "example.com/b@v1.2.0")
+
+	// MVS selected b v1.2.0, so only that version was packed
+	b := New("modules.txt", []Component{
+		{Path: "example.com/a", Version: "v1.0.0"},
+		{Path: "example.com/b", Version: "v1.2.0"},
+		{Path: "example.com/c", Version: "v1.0.0"},
+	}, g)
+	for _, parent := range []string{"example.com/a@v1.0.0", "example.com/c@v1.0.0"} {
+		deps := b.Dependencies[parent]
+		if len(deps) != 1 || deps[0] != "example.com/b@v1.2.0" {
+			t.Errorf("Dependencies of %s = %v, want b@v1.2.0", parent, deps)
+		}
+	}
+}
+
+func TestPURL(t *testing.T) {
+	c := Component{Path: "github.com/BurntSushi/toml", Version: "v1.3.2"}
+	if got, want := c.PURL(), "pkg:golang/github.com/BurntSushi/toml@v1.3.2"; got != want {
+		t.Errorf("PURL = %s, want %s", got, want)
+	}
+}
+
+func TestWriteCycloneDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBOM().WriteCycloneDX(&buf); err != nil {
+		t.Fatalf("WriteCycloneDX failed: %v", err)
+	}
+
+	var doc cdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc.BOMFormat != "CycloneDX" || len(doc.Components) != 2 {
+		t.Fatalf("Unexpected document: %+v", doc)
+	}
+	b := doc.Components[1]
+	if b.PURL != "pkg:golang/example.com/b@v1.0.0" || len(b.Hashes) != 1 || b.Hashes[0].Content != testZipSHA256 {
+		t.Errorf("Unexpected component: %+v", b)
+	}
+	if len(b.Properties) != 1 || b.Properties[0].Value != "h1:CrwycVLIkQiVbiQpiDQUWb331gUL92I6KZyAMNO8sjE=" {
+		t.Errorf("Unexpected properties: %+v", b.Properties)
+	}
+	if len(doc.Dependencies) != 3 || doc.Dependencies[0].DependsOn[0] != "pkg:golang/example.com/a@v1.0.0" {
+		t.Errorf("Unexpected dependencies: %+v", doc.Dependencies)
+	}
+}
+
+func TestWriteSPDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBOM().WriteSPDX(&buf); err != nil {
+		t.Fatalf("WriteSPDX failed: %v", err)
+	}
+
+	var doc spdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(doc.Packages) != 3 {
+		t.Fatalf("Got %d packages, want 3", len(doc.Packages))
+	}
+	if b := doc.Packages[2]; len(b.Checksums) != 1 || b.Checksums[0].ChecksumValue != testZipSHA256 {
+		t.Errorf("Unexpected checksums: %+v", b.Checksums)
+	}
+	if a := doc.Packages[1]; a.LicenseDeclared != "Apache-2.0 AND MIT" || a.SPDXID != "SPDXRef-Package-example.com-a-v1.0.0" {
+		t.Errorf("Unexpected package: %+v", a)
+	}
+	// DESCRIBES, root DEPENDS_ON a, a DEPENDS_ON b
+	if len(doc.Relationships) != 3 {
+		t.Errorf("Got relationships %+v", doc.Relationships)
+	}
+}
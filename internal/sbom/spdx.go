@@ -0,0 +1,138 @@
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const spdxNoAssertion = "NOASSERTION"
+
+var spdxIDInvalid = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// spdxID turns a component key into a valid SPDX element identifier.
+func spdxID(key string) string {
+	return "SPDXRef-Package-" + strings.Trim(spdxIDInvalid.ReplaceAllString(key, "-"), "-")
+}
+
+// WriteSPDX writes the BOM as an SPDX 2.3 JSON document.
+func (b *BOM) WriteSPDX(w io.Writer) error {
+	rootID := "SPDXRef-Package-modules.txt"
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              b.Name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + ToolName + "-" + newUUID(),
+		CreationInfo: spdxCreationInfo{
+			Created:  b.Timestamp.Format(time.RFC3339),
+			Creators: []string{"Tool: " + ToolName},
+		},
+		Packages: []spdxPackage{{
+			Name:             b.Name,
+			SPDXID:           rootID,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: rootID,
+		}},
+	}
+
+	for _, r := range b.Roots {
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      rootID,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: spdxID(r),
+		})
+	}
+
+	for _, c := range b.Components {
+		license := spdxNoAssertion
+		if len(c.Licenses) > 0 {
+			license = strings.Join(c.Licenses, " AND ")
+		}
+		pkg := spdxPackage{
+			Name:             c.Path,
+			SPDXID:           spdxID(c.Key()),
+			VersionInfo:      c.Version,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  license,
+			CopyrightText:    spdxNoAssertion,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  c.PURL(),
+			}},
+		}
+		if c.ZipSHA256 != "" {
+			pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: "SHA256", ChecksumValue: c.ZipSHA256})
+		}
+		doc.Packages = append(doc.Packages, pkg)
+
+		for _, d := range b.Dependencies[c.Key()] {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID:      spdxID(c.Key()),
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: spdxID(d),
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/accesslog"
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+)
+
+// requestState carries what handlers learn about a request, such as the
+// authenticated user, back to observe.
+type requestState struct {
+	user string
+}
+
+type stateKey struct{}
+
+// stateOf returns the state of a request wrapped by observe, or nil.
+func stateOf(r *http.Request) *requestState {
+	st, _ := r.Context().Value(stateKey{}).(*requestState)
+	return st
+}
+
+// observe wraps next to record each response in the metrics and the access
+// log.
+func (s *Server) observe(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		st := &requestState{}
+		r = r.WithContext(context.WithValue(r.Context(), stateKey{}, st))
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+
+		endpoint := endpointType(r.URL.Path)
+		s.metrics.record(endpoint, rec.status(), rec.bytes, elapsed)
+		if s.opts.AccessLog == nil {
+			return
+		}
+		e := accesslog.Entry{
+			Time:       start.UTC(),
+			ClientIP:   s.clientIP(r),
+			User:       st.user,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Artifact:   endpoint,
+			Status:     rec.status(),
+			Bytes:      rec.bytes,
+			DurationMS: float64(elapsed.Microseconds()) / 1000,
+		}
+		if endpoint != endpointOther && endpoint != endpointVulnDB {
+			e.Module = requestModulePath(r.URL.Path)
+			e.Version = requestVersion(r.URL.Path)
+		}
+		if err := s.opts.AccessLog.Log(e); err != nil {
+			log.Warn("Failed to write access log: %v", err)
+		}
+	})
+}
+
+// clientIP returns the address of the client, or with TrustProxy the last
+// address in X-Forwarded-For: the one the reverse proxy in front of the
+// server appended. Earlier addresses come from the client and can be forged.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.opts.TrustProxy {
+		fwd := strings.Join(r.Header.Values("X-Forwarded-For"), ",")
+		if i := strings.LastIndex(fwd, ","); i >= 0 {
+			fwd = fwd[i+1:]
+		}
+		if last := strings.TrimSpace(fwd); last != "" {
+			return last
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestVersion returns the unescaped version of an .info, .mod or .zip
+// request or the version of an upload, or "" for other requests.
+func requestVersion(urlPath string) string {
+	_, file, ok := strings.Cut(path.Clean("/"+urlPath), "/@v/")
+	if !ok || strings.Contains(file, "/") {
+		return ""
+	}
+	if strings.HasPrefix(urlPath, apiModulesPrefix) {
+		// Uploads name the plain version
+		return file
+	}
+	ext := path.Ext(file)
+	if ext != ".info" && ext != ".mod" && ext != ".zip" {
+		return ""
+	}
+	version := strings.TrimSuffix(file, ext)
+	if unescaped, err := gomod.UnescapePath(version); err == nil {
+		return unescaped
+	}
+	return version
+}
@@ -0,0 +1,340 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/license"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/quarantine"
+	"github.com/example/go-mod-clone/internal/storage"
+)
+
+// URL prefix and endpoints of the REST API.
+const (
+	apiPrefix        = "/api/v1/"
+	apiModulesURL    = "/api/v1/modules"
+	apiModulesPrefix = "/api/v1/modules/"
+	apiVersions      = "/versions"
+	apiUploadMarker  = "/@v/" // followed by the version to upload
+	apiStatsURL      = "/api/v1/stats"
+	apiSearchURL     = "/api/v1/search"
+)
+
+// Paging limits of the module list and search results.
+const (
+	defaultPerPage     = 100
+	maxPerPage         = 1000
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// apiModule is a module in the module list and search results.
+type apiModule struct {
+	Path     string     `json:"path"`
+	Versions int        `json:"versions"`
+	Latest   string     `json:"latest,omitempty"`
+	Time     *time.Time `json:"time,omitempty"` // release time of the latest version
+}
+
+// apiModuleList is the response of GET /api/v1/modules.
+type apiModuleList struct {
+	Modules  []apiModule `json:"modules"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PerPage  int         `json:"per_page"`
+	NextPage int         `json:"next_page,omitempty"`
+}
+
+// apiVersion is a version in GET /api/v1/modules/{path}/versions. The
+// hashes are the values go.sum records.
+type apiVersion struct {
+	Version   string     `json:"version"`
+	Time      *time.Time `json:"time,omitempty"`
+	Size      int64      `json:"size"`
+	ZipHash   string     `json:"zip_hash,omitempty"`
+	GoModHash string     `json:"go_mod_hash,omitempty"`
+	Licenses  []string   `json:"licenses,omitempty"`
+}
+
+type apiVersionList struct {
+	Path     string       `json:"path"`
+	Versions []apiVersion `json:"versions"`
+}
+
+// apiStats is the response of GET /api/v1/stats.
+type apiStats struct {
+	Modules         int   `json:"modules"`
+	Versions        int   `json:"versions"`
+	StorageBytes    int64 `json:"storage_bytes"`
+	PendingApproval int   `json:"pending_approval"`
+}
+
+type apiSearchResults struct {
+	Query   string      `json:"query"`
+	Results []apiModule `json:"results"`
+}
+
+// handleAPI serves the REST API. Lists only include the modules the user
+// may read; uploads also need the upload ACL's permission.
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request, user string) {
+	urlPath := strings.TrimSuffix(r.URL.Path, "/")
+	if rest, ok := strings.CutPrefix(urlPath, apiModulesPrefix); ok {
+		if modPath, version, ok := strings.Cut(rest, apiUploadMarker); ok {
+			if r.Method != http.MethodPut {
+				w.Header().Set("Allow", "PUT")
+				apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			s.apiUpload(w, r, user, modPath, version)
+			return
+		}
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	switch {
+	case urlPath == apiModulesURL:
+		s.apiModules(w, r, user)
+	case strings.HasPrefix(urlPath, apiModulesPrefix) && strings.HasSuffix(urlPath, apiVersions):
+		modPath := strings.TrimSuffix(strings.TrimPrefix(urlPath, apiModulesPrefix), apiVersions)
+		s.apiVersions(w, user, modPath)
+	case urlPath == apiStatsURL:
+		s.apiStats(w)
+	case urlPath == apiSearchURL:
+		s.apiSearch(w, r, user)
+	default:
+		apiError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// apiModules lists modules by path, filtered by the prefix parameter and
+// paged with the page and per_page parameters.
+func (s *Server) apiModules(w http.ResponseWriter, r *http.Request, user string) {
+	q := r.URL.Query()
+	page, err := intParam(q.Get("page"), 1, 1, 0)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid page")
+		return
+	}
+	perPage, err := intParam(q.Get("per_page"), defaultPerPage, 1, maxPerPage)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid per_page")
+		return
+	}
+	prefix := q.Get("prefix")
+	modules, err := s.listModules(user, func(modPath string) bool {
+		return strings.HasPrefix(modPath, prefix)
+	})
+	if err != nil {
+		log.Error("Failed to list modules: %v", err)
+		apiError(w, http.StatusInternalServerError, "failed to list modules")
+		return
+	}
+
+	list := apiModuleList{Modules: []apiModule{}, Total: len(modules), Page: page, PerPage: perPage}
+	// Pages past the end are empty; checking before multiplying keeps huge
+	// page numbers from overflowing
+	if page-1 < (len(modules)+perPage-1)/perPage {
+		start := (page - 1) * perPage
+		end := start + perPage
+		if end < len(modules) {
+			list.NextPage = page + 1
+		} else {
+			end = len(modules)
+		}
+		list.Modules = toAPIModules(modules[start:end])
+	}
+	writeJSON(w, list)
+}
+
+// apiVersions lists the versions of a module, oldest first.
+func (s *Server) apiVersions(w http.ResponseWriter, user, modPath string) {
+	atVDir, ok := s.moduleDir(user, modPath)
+	if !ok {
+		apiError(w, http.StatusNotFound, "module not found")
+		return
+	}
+	list := apiVersionList{Path: modPath, Versions: []apiVersion{}}
+	for _, version := range storage.ReadList(atVDir) {
+		v := apiVersion{Version: version}
+		if t := readInfoTime(storage.VersionFile(atVDir, version, ".info")); !t.IsZero() {
+			v.Time = &t
+		}
+		zipFile := storage.VersionFile(atVDir, version, ".zip")
+		if info, err := os.Stat(zipFile); err == nil {
+			v.Size = info.Size()
+			v.ZipHash = s.hashes.get(zipFile, info, modzip.HashZip)
+		}
+		modFile := storage.VersionFile(atVDir, version, ".mod")
+		if info, err := os.Stat(modFile); err == nil {
+			v.GoModHash = s.hashes.get(modFile, info, modzip.HashGoMod)
+		}
+		if report, err := license.ReadMetadata(storage.VersionFile(atVDir, version, license.MetadataExt)); err == nil {
+			v.Licenses = report.Licenses
+		}
+		list.Versions = append(list.Versions, v)
+	}
+	writeJSON(w, list)
+}
+
+// apiStats reports the size of the mirror. The counts cover every module,
+// not only those the user may read.
+func (s *Server) apiStats(w http.ResponseWriter) {
+	modules, err := storage.ListModules(s.storageRoot)
+	if err != nil {
+		log.Error("Failed to list modules: %v", err)
+		apiError(w, http.StatusInternalServerError, "failed to list modules")
+		return
+	}
+	bytes, versions := s.metrics.storage.get()
+	stats := apiStats{Modules: len(modules), Versions: versions, StorageBytes: bytes}
+	if pending, err := quarantine.Pending(s.storageRoot); err == nil {
+		for _, m := range pending {
+			stats.PendingApproval += len(m.Versions)
+		}
+	}
+	writeJSON(w, stats)
+}
+
+// apiSearch finds modules whose path contains the q parameter, ignoring
+// case. Exact matches of the path or its last element rank first, then
+// matches at the start of an element, then other matches.
+func (s *Server) apiSearch(w http.ResponseWriter, r *http.Request, user string) {
+	q := r.URL.Query()
+	query := strings.ToLower(strings.TrimSpace(q.Get("q")))
+	if query == "" {
+		apiError(w, http.StatusBadRequest, "missing q")
+		return
+	}
+	limit, err := intParam(q.Get("limit"), defaultSearchLimit, 1, maxSearchLimit)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	modules, err := s.listModules(user, func(modPath string) bool {
+		return strings.Contains(strings.ToLower(modPath), query)
+	})
+	if err != nil {
+		log.Error("Failed to list modules: %v", err)
+		apiError(w, http.StatusInternalServerError, "failed to list modules")
+		return
+	}
+	sort.SliceStable(modules, func(i, j int) bool {
+		return searchRank(modules[i].Path, query) < searchRank(modules[j].Path, query)
+	})
+	if len(modules) > limit {
+		modules = modules[:limit]
+	}
+	writeJSON(w, apiSearchResults{Query: q.Get("q"), Results: toAPIModules(modules)})
+}
+
+// searchRank orders search results; lower is better.
+func searchRank(modPath, query string) int {
+	p := strings.ToLower(modPath)
+	last := p[strings.LastIndex(p, "/")+1:]
+	switch {
+	case p == query || last == query:
+		return 0
+	case strings.HasPrefix(last, query):
+		return 1
+	case strings.HasPrefix(p, query) || strings.Contains(p, "/"+query) || strings.Contains(p, "."+query):
+		return 2
+	}
+	return 3
+}
+
+func toAPIModules(modules []uiModule) []apiModule {
+	readTimes(modules)
+	out := make([]apiModule, 0, len(modules))
+	for _, m := range modules {
+		am := apiModule{Path: m.Path, Versions: m.Versions, Latest: m.Latest}
+		if !m.Time.IsZero() {
+			t := m.Time
+			am.Time = &t
+		}
+		out = append(out, am)
+	}
+	return out
+}
+
+// intParam parses an optional integer parameter within [min, max]; a max
+// of 0 means no upper bound.
+func intParam(value string, def, min, max int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < min {
+		return 0, strconv.ErrRange
+	}
+	if max > 0 && n > max {
+		n = max
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Debug("Failed to write response: %v", err)
+	}
+}
+
+func apiError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{msg})
+}
+
+// hashCache remembers the hashes of module files until they change, as
+// hashing a large zip takes a while.
+type hashCache struct {
+	mu     sync.Mutex
+	hashes map[string]cachedHash
+}
+
+type cachedHash struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+// get returns the hash of file, computing it with hash if the file is new
+// or changed. It returns "" if the file cannot be hashed.
+func (c *hashCache) get(file string, info os.FileInfo, hash func(string) (string, error)) string {
+	c.mu.Lock()
+	cached, ok := c.hashes[file]
+	c.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.hash
+	}
+	h, err := hash(file)
+	if err != nil {
+		log.Warn("Failed to hash %s: %v", file, err)
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hashes == nil {
+		c.hashes = make(map[string]cachedHash)
+	}
+	c.hashes[file] = cachedHash{modTime: info.ModTime(), size: info.Size(), hash: h}
+	return h
+}
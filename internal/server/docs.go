@@ -0,0 +1,332 @@
+package server
+
+import (
+	"errors"
+	"go/doc"
+	"go/doc/comment"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/moddoc"
+	"github.com/example/go-mod-clone/internal/storage"
+)
+
+// URL prefixes of the package documentation and the source viewer, followed
+// by module@version and a directory or file in the module.
+const (
+	uiDocPrefix = "/ui/doc/"
+	uiSrcPrefix = "/ui/src/"
+)
+
+// uiDoc is the documentation of a package.
+type uiDoc struct {
+	ImportPath string
+	Name       string
+	Doc        template.HTML
+	Examples   []uiExample
+	Consts     []uiDecl
+	Vars       []uiDecl
+	Funcs      []uiDecl
+	Types      []uiType
+}
+
+// uiDecl is a documented declaration.
+type uiDecl struct {
+	ID       string
+	Name     string
+	Code     string
+	Doc      template.HTML
+	Examples []uiExample
+}
+
+type uiType struct {
+	Decl    uiDecl
+	Consts  []uiDecl
+	Vars    []uiDecl
+	Funcs   []uiDecl
+	Methods []uiDecl
+}
+
+type uiExample struct {
+	Name   string
+	Doc    template.HTML
+	Code   string
+	Output string
+}
+
+// uiEntry is a directory entry of the source viewer.
+type uiEntry struct {
+	moddoc.Entry
+	Path string // relative to the module root
+}
+
+// uiSource is the data of the documentation and source pages.
+type uiSource struct {
+	Dir      string // directory or file relative to the module root
+	Crumbs   []uiEntry
+	Packages []moddoc.Package
+	Doc      *uiDoc
+	Entries  []uiEntry
+	File     string // contents of a text file
+	Binary   bool
+	Size     int64
+}
+
+// splitModuleVersion splits "<module>@<version>[/<path>]" from a doc or
+// source URL.
+func splitModuleVersion(s string) (modPath, version, rest string, ok bool) {
+	modPath, after, ok := strings.Cut(s, "@")
+	if !ok {
+		return "", "", "", false
+	}
+	version, rest, _ = strings.Cut(after, "/")
+	rest = strings.Trim(rest, "/")
+	if version == "" || strings.Contains(version, `\`) || (rest != "" && path.Clean(rest) != rest) {
+		return "", "", "", false
+	}
+	return modPath, version, rest, true
+}
+
+// openModule opens the zip of a module version the user may read.
+func (s *Server) openModule(w http.ResponseWriter, r *http.Request, user, prefix string) (*moddoc.Module, string, bool) {
+	modPath, version, rest, ok := splitModuleVersion(strings.TrimPrefix(r.URL.Path, prefix))
+	if !ok {
+		http.NotFound(w, r)
+		return nil, "", false
+	}
+	atVDir, ok := s.moduleDir(user, modPath)
+	if !ok {
+		http.NotFound(w, r)
+		return nil, "", false
+	}
+	m, err := moddoc.Open(storage.VersionFile(atVDir, version, ".zip"), modPath, version)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+		} else {
+			log.Error("Failed to open %s@%s: %v", modPath, version, err)
+			http.Error(w, "failed to open module", http.StatusInternalServerError)
+		}
+		return nil, "", false
+	}
+	return m, rest, true
+}
+
+// docUI shows the documentation of the package in a directory of a module
+// version, and the packages below it.
+func (s *Server) docUI(w http.ResponseWriter, r *http.Request, user string) {
+	m, dir, ok := s.openModule(w, r, user, uiDocPrefix)
+	if !ok {
+		return
+	}
+	defer m.Close()
+
+	src := &uiSource{Dir: dir, Crumbs: crumbs(dir)}
+	for _, p := range s.packages.get(m) {
+		if p.Dir != dir && (dir == "" || strings.HasPrefix(p.Dir, dir+"/")) {
+			src.Packages = append(src.Packages, p)
+		}
+	}
+	d, err := m.Doc(dir)
+	switch {
+	case err == nil:
+		src.Doc = docData(d, m)
+	case errors.Is(err, fs.ErrNotExist):
+		if len(src.Packages) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+	default:
+		log.Warn("Failed to read documentation of %s@%s/%s: %v", m.Path, m.Version, dir, err)
+	}
+
+	title := m.Path
+	if dir != "" {
+		title += "/" + dir
+	}
+	page := s.newPage(r, user, title)
+	page.Module = m.Path
+	page.Version = &uiVersion{Version: m.Version}
+	page.Source = src
+	s.render(w, "doc.html", page)
+}
+
+// srcUI lists a directory of a module version or shows one of its files.
+func (s *Server) srcUI(w http.ResponseWriter, r *http.Request, user string) {
+	m, name, ok := s.openModule(w, r, user, uiSrcPrefix)
+	if !ok {
+		return
+	}
+	defer m.Close()
+
+	isDir, ok := m.Stat(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	src := &uiSource{Dir: name, Crumbs: crumbs(name)}
+	if isDir {
+		for _, e := range m.ReadDir(name) {
+			src.Entries = append(src.Entries, uiEntry{Entry: e, Path: path.Join(name, e.Name)})
+		}
+	} else {
+		for _, f := range m.Files() {
+			if f.Name == name {
+				src.Size = f.Size
+			}
+		}
+		data, err := m.ReadFile(name)
+		switch {
+		case err == nil && moddoc.IsText(data):
+			src.File = string(data)
+		case err == nil || errors.Is(err, moddoc.ErrTooLarge):
+			src.Binary = true
+		default:
+			log.Error("Failed to read %s from %s@%s: %v", name, m.Path, m.Version, err)
+			http.Error(w, "failed to read file", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	page := s.newPage(r, user, m.Path+"@"+m.Version+"/"+name)
+	page.Module = m.Path
+	page.Version = &uiVersion{Version: m.Version}
+	page.Source = src
+	s.render(w, "src.html", page)
+}
+
+// crumbs returns the parent directories of a path, and the path itself.
+func crumbs(name string) []uiEntry {
+	if name == "" {
+		return nil
+	}
+	var entries []uiEntry
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		entries = append(entries, uiEntry{
+			Entry: moddoc.Entry{Name: elem, IsDir: i < len(elems)-1},
+			Path:  strings.Join(elems[:i+1], "/"),
+		})
+	}
+	return entries
+}
+
+// docData converts the documentation of a package for the template. Doc
+// links to packages of the same module point to their pages; others are
+// left as text, as the documentation may not be mirrored.
+func docData(d *moddoc.Doc, m *moddoc.Module) *uiDoc {
+	printer := d.Printer()
+	printer.DocLinkURL = func(link *comment.DocLink) string {
+		name := link.Name
+		if link.Recv != "" {
+			name = link.Recv + "." + name
+		}
+		switch {
+		case link.ImportPath == "":
+			return "#" + name
+		case link.ImportPath == m.Path || strings.HasPrefix(link.ImportPath, m.Path+"/"):
+			u := docPage(m.Path, m.Version, strings.TrimPrefix(strings.TrimPrefix(link.ImportPath, m.Path), "/"))
+			if name != "" {
+				u += "#" + name
+			}
+			return u
+		}
+		return ""
+	}
+	html := func(text string) template.HTML {
+		return template.HTML(printer.HTML(d.Parser().Parse(text)))
+	}
+	examples := func(exs []*doc.Example) []uiExample {
+		var out []uiExample
+		for _, ex := range exs {
+			out = append(out, uiExample{
+				Name:   ex.Suffix,
+				Doc:    html(ex.Doc),
+				Code:   d.Example(ex),
+				Output: ex.Output,
+			})
+		}
+		return out
+	}
+	values := func(vs []*doc.Value) []uiDecl {
+		var out []uiDecl
+		for _, v := range vs {
+			out = append(out, uiDecl{ID: v.Names[0], Code: d.Decl(v.Decl), Doc: html(v.Doc)})
+		}
+		return out
+	}
+	funcs := func(fs []*doc.Func, recv string) []uiDecl {
+		var out []uiDecl
+		for _, f := range fs {
+			id := f.Name
+			if recv != "" {
+				id = recv + "." + f.Name
+			}
+			out = append(out, uiDecl{ID: id, Name: f.Name, Code: d.Decl(f.Decl), Doc: html(f.Doc), Examples: examples(f.Examples)})
+		}
+		return out
+	}
+
+	data := &uiDoc{
+		ImportPath: d.ImportPath,
+		Name:       d.Name,
+		Doc:        html(d.Package.Doc),
+		Examples:   examples(d.Examples),
+		Consts:     values(d.Consts),
+		Vars:       values(d.Vars),
+		Funcs:      funcs(d.Funcs, ""),
+	}
+	for _, t := range d.Types {
+		data.Types = append(data.Types, uiType{
+			Decl:    uiDecl{ID: t.Name, Name: t.Name, Code: d.Decl(t.Decl), Doc: html(t.Doc), Examples: examples(t.Examples)},
+			Consts:  values(t.Consts),
+			Vars:    values(t.Vars),
+			Funcs:   funcs(t.Funcs, ""),
+			Methods: funcs(t.Methods, t.Name),
+		})
+	}
+	return data
+}
+
+// docPage returns the UI URL of the documentation of a directory of a
+// module version.
+func docPage(modPath, version, dir string) string {
+	return strings.TrimSuffix(uiDocPrefix+modPath+"@"+version+"/"+dir, "/")
+}
+
+// srcPage returns the UI URL of a file or directory of a module version.
+func srcPage(modPath, version, name string) string {
+	return strings.TrimSuffix(uiSrcPrefix+modPath+"@"+version+"/"+name, "/")
+}
+
+// packageCache remembers the packages of module versions, as listing them
+// parses every Go file of the module. Published versions never change, so
+// entries are kept as long as the server runs.
+type packageCache struct {
+	mu   sync.Mutex
+	pkgs map[string][]moddoc.Package // by path@version
+}
+
+// get returns the packages of m, listing them on first use.
+func (c *packageCache) get(m *moddoc.Module) []moddoc.Package {
+	key := m.Path + "@" + m.Version
+	c.mu.Lock()
+	pkgs, ok := c.pkgs[key]
+	c.mu.Unlock()
+	if ok {
+		return pkgs
+	}
+	pkgs = m.Packages()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pkgs == nil {
+		c.pkgs = make(map[string][]moddoc.Package)
+	}
+	c.pkgs[key] = pkgs
+	return pkgs
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/metrics"
+)
+
+// storageScanInterval limits how often a scrape walks the storage root.
+const storageScanInterval = time.Minute
+
+// Endpoint types that requests are counted by.
+const (
+	endpointList   = "list"
+	endpointInfo   = "info"
+	endpointMod    = "mod"
+	endpointZip    = "zip"
+	endpointLatest = "latest"
+	endpointVulnDB = "vulndb"
+	endpointUpload = "upload"
+	endpointOther  = "other"
+)
+
+// serverMetrics are the metrics served on /metrics.
+type serverMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.CounterVec
+	bytes    *metrics.CounterVec
+	duration *metrics.HistogramVec
+	storage  *storageStats
+}
+
+func newServerMetrics(storageRoot string) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: r,
+		requests: r.Counter("gomodclone_http_requests_total", "Requests by endpoint type and status code.", "endpoint", "code"),
+		bytes:    r.Counter("gomodclone_http_response_bytes_total", "Response body bytes served by endpoint type.", "endpoint"),
+		duration: r.Histogram("gomodclone_http_request_duration_seconds", "Request latency by endpoint type.", metrics.DefaultBuckets, "endpoint"),
+		storage:  &storageStats{root: storageRoot},
+	}
+	r.GaugeFunc("gomodclone_storage_bytes", "Size of the files in the storage root.", func() float64 {
+		bytes, _ := m.storage.get()
+		return float64(bytes)
+	})
+	r.GaugeFunc("gomodclone_storage_module_versions", "Module versions with a zip in the storage root.", func() float64 {
+		_, versions := m.storage.get()
+		return float64(versions)
+	})
+	return m
+}
+
+// record counts a response.
+func (m *serverMetrics) record(endpoint string, code int, bytes int64, elapsed time.Duration) {
+	m.requests.Inc(endpoint, strconv.Itoa(code))
+	m.bytes.Add(float64(bytes), endpoint)
+	m.duration.Observe(elapsed.Seconds(), endpoint)
+}
+
+// endpointType classifies a request path by the GOPROXY protocol endpoint.
+func endpointType(urlPath string) string {
+	switch {
+	case strings.HasPrefix(urlPath, apiModulesPrefix) && strings.Contains(urlPath, apiUploadMarker):
+		return endpointUpload
+	case strings.HasPrefix(urlPath, "/vulndb/"):
+		return endpointVulnDB
+	case strings.HasSuffix(urlPath, "/@latest"):
+		return endpointLatest
+	case strings.HasSuffix(urlPath, "/@v/list"):
+		return endpointList
+	case !strings.Contains(urlPath, "/@v/"):
+		return endpointOther
+	}
+	switch filepath.Ext(urlPath) {
+	case ".info":
+		return endpointInfo
+	case ".mod":
+		return endpointMod
+	case ".zip":
+		return endpointZip
+	}
+	return endpointOther
+}
+
+// responseRecorder records the status code and body size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the underlying writer's sendfile path for zip downloads.
+func (r *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := r.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(r.ResponseWriter, src)
+	}
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the connection, for the read
+// deadline of uploads.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
+
+// storageStats caches the size of the storage root between scans.
+type storageStats struct {
+	root string
+
+	mu       sync.Mutex
+	scanned  time.Time
+	bytes    int64
+	versions int
+}
+
+// get returns the total size of the storage root, including tool state,
+// and the number of served module versions.
+func (s *storageStats) get() (int64, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.scanned) < storageScanInterval {
+		return s.bytes, s.versions
+	}
+	var bytes int64
+	versions := 0
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			bytes += info.Size()
+		}
+		rel, _ := filepath.Rel(s.root, path)
+		if strings.HasSuffix(path, ".zip") && filepath.Base(filepath.Dir(path)) == "@v" && !isHidden("/"+filepath.ToSlash(rel)) {
+			versions++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn("Failed to measure storage root: %v", err)
+	}
+	s.bytes, s.versions, s.scanned = bytes, versions, time.Now()
+	return bytes, versions
+}
@@ -1,52 +1,316 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
-	"path/filepath"
+	"os"
+	"os/signal"
+	"path"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/example/go-mod-clone/internal/accesslog"
+	"github.com/example/go-mod-clone/internal/auth"
+	"github.com/example/go-mod-clone/internal/gomod"
 	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/packer"
+	"github.com/example/go-mod-clone/internal/policy"
 )
 
 type Server struct {
 	storageRoot string
 	host        string
 	port        int
+	opts        Options
+	metrics     *serverMetrics
+	hashes      hashCache
+	packages    packageCache
+	uploadMu    sync.Mutex
+	draining    atomic.Bool
 }
 
+// Options configures TLS, access control and the HTTP server's limits.
+// Without a certificate the server speaks plain HTTP; without an
+// authenticator anyone may read. Zero limits take the defaults below.
+type Options struct {
+	TLSCert      string             // PEM certificate chain
+	TLSKey       string             // PEM private key
+	ClientCA     string             // PEM CA bundle; clients must present a certificate it signed, except to the probes
+	RedirectHTTP string             // address of a plain HTTP listener redirecting to HTTPS
+	Auth         auth.Authenticator // identifies users; requests without valid credentials get 401
+	ACL          *auth.ACL          // module path prefixes each user may read; nil allows all users
+	AccessLog    *accesslog.Logger  // records every module request; nil disables
+	TrustProxy   bool               // take the client IP from the last X-Forwarded-For entry
+	Uploads      *packer.Packer     // packs versions uploaded through the API; nil disables uploads
+	UploadACL    *auth.ACL          // module path prefixes each user may upload to
+	Policy       *policy.Policy     // path and version rules uploads must satisfy; Uploads checks licenses
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	UploadTimeout     time.Duration // replaces ReadTimeout for authorized uploads, which can be large; negative disables
+	WriteTimeout      time.Duration // negative disables; large zips on slow links take a while
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration // how long in-flight requests may finish after SIGTERM
+	DrainDelay        time.Duration // how long readiness fails before connections are refused
+}
+
+// Defaults of the HTTP server limits.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = time.Minute
+	DefaultUploadTimeout     = 30 * time.Minute
+	DefaultWriteTimeout      = 10 * time.Minute
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultMaxHeaderBytes    = 64 << 10
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
 func NewServer(storageRoot, host string, port int) *Server {
+	return NewServerWithOptions(storageRoot, host, port, Options{})
+}
+
+func NewServerWithOptions(storageRoot, host string, port int, opts Options) *Server {
+	if opts.ReadHeaderTimeout == 0 {
+		opts.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if opts.ReadTimeout == 0 {
+		opts.ReadTimeout = DefaultReadTimeout
+	}
+	if opts.UploadTimeout == 0 {
+		opts.UploadTimeout = DefaultUploadTimeout
+	}
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = DefaultWriteTimeout
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	if opts.MaxHeaderBytes == 0 {
+		opts.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if opts.ShutdownTimeout == 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
 	return &Server{
 		storageRoot: storageRoot,
 		host:        host,
 		port:        port,
+		opts:        opts,
+		metrics:     newServerMetrics(storageRoot),
+	}
+}
+
+// scheme returns the URL scheme clients use to reach the server.
+func (s *Server) scheme() string {
+	if s.opts.TLSCert != "" {
+		return "https"
+	}
+	return "http"
+}
+
+func (s *Server) validate() error {
+	if (s.opts.TLSCert == "") != (s.opts.TLSKey == "") {
+		return fmt.Errorf("a TLS certificate needs both --tls-cert and --tls-key")
+	}
+	if s.opts.TLSCert == "" && (s.opts.ClientCA != "" || s.opts.RedirectHTTP != "") {
+		return fmt.Errorf("--client-ca and --redirect-http need --tls-cert and --tls-key")
 	}
+	return nil
 }
 
+// Start serves until SIGTERM or SIGINT, then stops accepting connections and
+// gives in-flight requests up to the shutdown timeout to finish.
 func (s *Server) Start() error {
+	if err := s.validate(); err != nil {
+		return err
+	}
 	log.Info("Starting Go module proxy server")
 	log.Info("Storage root: %s", s.storageRoot)
 	log.Info("Listening on %s:%d", s.host, s.port)
 
-	// Create file server
-	fs := http.FileServer(http.Dir(s.storageRoot))
+	ln, err := net.Listen("tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	return s.serve(ctx, ln)
+}
 
-	// Create router with custom handler
+// Handler returns the server's routes. The probes answer without
+// credentials so that load balancers can reach them, the metrics without
+// credentials other than a client certificate under mutual TLS, and neither
+// is counted in the request metrics.
+func (s *Server) Handler() http.Handler {
+	fs := http.FileServer(http.Dir(s.storageRoot))
 	mux := http.NewServeMux()
-	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	mux.Handle("/metrics", s.requireClientCert(s.metrics.registry.Handler()))
+	mux.Handle("/", s.observe(s.requireClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s.handleRequest(w, r, fs)
-	}))
+	}))))
+	return mux
+}
+
+// requireClientCert refuses requests without a verified client certificate
+// when a client CA is configured. The TLS handshake lets such clients in so
+// that the probes work for them.
+func (s *Server) requireClientCert(next http.Handler) http.Handler {
+	if s.opts.ClientCA == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "403 Forbidden: client certificate required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-	// Start server
-	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+// httpServer returns an http.Server with the configured limits.
+func (s *Server) httpServer(handler http.Handler) *http.Server {
 	server := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Handler:           handler,
+		ReadHeaderTimeout: s.opts.ReadHeaderTimeout,
+		ReadTimeout:       s.opts.ReadTimeout,
+		WriteTimeout:      s.opts.WriteTimeout,
+		IdleTimeout:       s.opts.IdleTimeout,
+		MaxHeaderBytes:    s.opts.MaxHeaderBytes,
+	}
+	if server.WriteTimeout < 0 {
+		server.WriteTimeout = 0
+	}
+	return server
+}
+
+// serve runs the server on ln until ctx is done.
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	servers := []*http.Server{s.httpServer(s.Handler())}
+	main := servers[0]
+
+	errc := make(chan error, 2)
+	if s.opts.TLSCert == "" {
+		log.Info("Server started. Use GOPROXY=http://%s:%d go get ...", s.host, s.port)
+		go func() { errc <- main.Serve(ln) }()
+	} else {
+		certs, err := newCertReloader(s.opts.TLSCert, s.opts.TLSKey, s.opts.ClientCA)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		stop := make(chan struct{})
+		defer close(stop)
+		go certs.watch(stop)
+		main.TLSConfig = certs.tlsConfig()
+
+		if s.opts.RedirectHTTP != "" {
+			redirect := s.redirectServer()
+			servers = append(servers, redirect)
+			go func() { errc <- redirect.ListenAndServe() }()
+		}
+		if s.opts.ClientCA != "" {
+			log.Info("Client certificates signed by %s are required", s.opts.ClientCA)
+		}
+		log.Info("Server started. Use GOPROXY=https://%s:%d go get ...", s.host, s.port)
+		go func() { errc <- main.ServeTLS(ln, "", "") }()
+	}
+
+	select {
+	case err := <-errc:
+		if err != http.ErrServerClosed {
+			for _, srv := range servers {
+				srv.Close()
+			}
+			return err
+		}
+	case <-ctx.Done():
+	}
+
+	// Fail readiness first and keep serving for the drain delay, so that
+	// load balancers stop sending requests before connections are refused,
+	// then wait for the in-flight ones
+	s.draining.Store(true)
+	if s.opts.DrainDelay > 0 {
+		log.Info("Shutting down, failing readiness for %s before closing the listener", s.opts.DrainDelay)
+		time.Sleep(s.opts.DrainDelay)
 	}
+	log.Info("Shutting down, waiting up to %s for in-flight requests", s.opts.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+	defer cancel()
+	var shutdownErr error
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+			shutdownErr = fmt.Errorf("shutdown timed out, closed remaining connections: %w", err)
+		}
+	}
+	if shutdownErr != nil {
+		log.Warn("%v", shutdownErr)
+		return shutdownErr
+	}
+	log.Info("Server stopped")
+	return nil
+}
 
-	log.Info("Server started. Use GOPROXY=http://%s:%d go get ...", s.host, s.port)
-	return server.ListenAndServe()
+// handleHealthz reports that the process is up.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports whether the server can serve modules: it is not
+// shutting down and the storage root is readable.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if s.draining.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	f, err := os.Open(s.storageRoot)
+	if err == nil {
+		_, err = f.Readdirnames(1)
+		f.Close()
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Warn("Readiness check failed: %v", err)
+		http.Error(w, "storage root not readable", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ready")
+}
+
+// redirectServer returns the plain HTTP listener that sends every request
+// to the same path over HTTPS.
+func (s *Server) redirectServer() *http.Server {
+	log.Info("Redirecting HTTP on %s to HTTPS", s.opts.RedirectHTTP)
+	server := s.httpServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, s.redirectURL(r), http.StatusMovedPermanently)
+	}))
+	server.Addr = s.opts.RedirectHTTP
+	return server
+}
+
+// redirectURL returns the HTTPS URL of a plain HTTP request, keeping the
+// host name the client used.
+func (s *Server) redirectURL(r *http.Request) string {
+	hostname := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		hostname = h
+	}
+	return "https://" + net.JoinHostPort(hostname, strconv.Itoa(s.port)) + r.URL.RequestURI()
 }
 
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request, fs http.Handler) {
@@ -55,22 +319,100 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request, fs http.H
 	// Log request
 	log.Debug("Request: %s %s", r.Method, path)
 
-	// Handle root path
-	if path == "/" || path == "" {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `<html>
-<head><title>Go Module Proxy</title></head>
-<body>
-<h1>Go Module Proxy Server</h1>
-<p>This is a Go module proxy server running from %s</p>
-<p>Configure your Go environment:</p>
-<pre>export GOPROXY=http://%s:%d
-go get github.com/user/module@version</pre>
-</body>
-</html>`, filepath.Base(s.storageRoot), s.host, s.port)
+	user, ok := s.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	// The web UI is on the root page and below /ui/
+	if path == "/" || path == "" || strings.HasPrefix(path, uiPrefix) {
+		s.handleUI(w, r, user)
+		return
+	}
+	if strings.HasPrefix(path, apiPrefix) {
+		s.handleAPI(w, r, user)
+		return
+	}
+
+	// Tool state such as the quarantine area is never served
+	if isHidden(path) {
+		http.NotFound(w, r)
 		return
 	}
 
 	// Serve files from storage root
 	fs.ServeHTTP(w, r)
 }
+
+// authorize checks the credentials of a request against the configured
+// authenticator and ACL, writing a 401 or 403 response if access is denied.
+// It returns the authenticated user, "" without an authenticator.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if s.opts.Auth == nil {
+		return "", true
+	}
+	user := s.opts.Auth.Authenticate(r)
+	if st := stateOf(r); st != nil {
+		st.user = user
+	}
+	if user == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="go-mod-clone"`)
+		http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	if s.opts.ACL != nil && !aclExempt(r.URL.Path) && !s.opts.ACL.Allowed(user, requestModulePath(r.URL.Path)) {
+		log.Debug("Denied %s access to %s", user, r.URL.Path)
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return "", false
+	}
+	return user, true
+}
+
+// aclExempt reports whether any authenticated user may request a path: the
+// root page and the API's lists, which only include the modules the user
+// may read, the UI's static files and the vulnerability database, which is
+// public data about every module.
+func aclExempt(urlPath string) bool {
+	switch strings.TrimSuffix(urlPath, "/") {
+	case "", apiModulesURL, apiStatsURL, apiSearchURL:
+		return true
+	}
+	return strings.HasPrefix(urlPath, uiStaticPrefix) || strings.HasPrefix(path.Clean("/"+urlPath), "/vulndb/")
+}
+
+// requestModulePath returns the module path a request is for: the part
+// before /@v/ or /@latest, unescaped, or the module of a UI page or API
+// request. Other paths are returned as is.
+func requestModulePath(urlPath string) string {
+	p := strings.Trim(path.Clean("/"+urlPath), "/")
+	for _, prefix := range []string{uiModulePrefix, uiDocPrefix, uiSrcPrefix} {
+		if modPath, ok := strings.CutPrefix("/"+p, prefix); ok {
+			// UI pages use the plain module path, followed by @version
+			modPath, _, _ = strings.Cut(modPath, "@")
+			return modPath
+		}
+	}
+	if modPath, ok := strings.CutPrefix("/"+p, apiModulesPrefix); ok {
+		// The API uses the plain module path too
+		modPath, _, _ = strings.Cut(modPath, apiUploadMarker)
+		return strings.TrimSuffix(modPath, apiVersions)
+	}
+	for _, marker := range []string{"/@v/", "/@latest"} {
+		if i := strings.Index(p+"/", marker); i >= 0 {
+			p = p[:i]
+			break
+		}
+	}
+	if unescaped, err := gomod.UnescapePath(p); err == nil {
+		return unescaped
+	}
+	return p
+}
+
+// isHidden reports whether a request path is inside a top-level directory
+// whose name starts with "." or "_". Module paths never do, so these hold
+// tool state, including versions awaiting approval.
+func isHidden(urlPath string) bool {
+	first := strings.SplitN(strings.TrimPrefix(path.Clean("/"+urlPath), "/"), "/", 2)[0]
+	return strings.HasPrefix(first, ".") || strings.HasPrefix(first, "_")
+}
@@ -0,0 +1,991 @@
+package server
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/accesslog"
+	"github.com/example/go-mod-clone/internal/auth"
+	"github.com/example/go-mod-clone/internal/moddoc"
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/packer"
+)
+
+// issue creates a certificate for name, signed by parent (self-signed if
+// nil), and returns it with its key.
+func issue(t *testing.T, name string, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertReloader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	_, _, certPEM, keyPEM := issue(t, "localhost", 1, nil, nil, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	r, err := newCertReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	if r.changed() {
+		t.Error("Files reported changed right after loading")
+	}
+
+	// A broken renewal keeps the previous certificate
+	writeFile(t, certFile, []byte("garbage"))
+	r.reload("test")
+	if cert, _ := r.getCertificate(nil); cert == nil {
+		t.Fatal("Certificate lost after failed reload")
+	}
+
+	_, _, certPEM, keyPEM = issue(t, "localhost", 2, nil, nil, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	if !r.changed() {
+		t.Error("Rewritten certificate not reported as changed")
+	}
+	r.reload("test")
+	cert, _ := r.getCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || leaf.SerialNumber.Int64() != 2 {
+		t.Errorf("Reloaded certificate has serial %v, want 2", leaf.SerialNumber)
+	}
+}
+
+func TestCertReloader_RequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caPEM, _ := issue(t, "test CA", 1, nil, nil, true)
+	_, _, serverPEM, serverKeyPEM := issue(t, "localhost", 2, ca, caKey, false)
+	_, _, clientPEM, clientKeyPEM := issue(t, "client", 3, ca, caKey, false)
+	writeFile(t, filepath.Join(dir, "ca.crt"), caPEM)
+	writeFile(t, filepath.Join(dir, "tls.crt"), serverPEM)
+	writeFile(t, filepath.Join(dir, "tls.key"), serverKeyPEM)
+
+	r, err := newCertReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	root := t.TempDir()
+	atV := filepath.Join(root, "example.com", "a", "@v")
+	os.MkdirAll(atV, 0755)
+	writeFile(t, filepath.Join(atV, "list"), []byte("v1.0.0\n"))
+	s := NewServerWithOptions(root, "localhost", 0, Options{ClientCA: filepath.Join(dir, "ca.crt")})
+	srv := httptest.NewUnstartedServer(s.Handler())
+	srv.EnableHTTP2 = true
+	srv.TLS = r.tlsConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: certs},
+			ForceAttemptHTTP2: true,
+		}}
+	}
+
+	get := func(c *http.Client, path string) *http.Response {
+		t.Helper()
+		resp, err := c.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Clients without a certificate only reach the probes
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/healthz", http.StatusOK},
+		{"/readyz", http.StatusOK},
+		{"/example.com/a/@v/list", http.StatusForbidden},
+		{"/metrics", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if resp := get(client(), tt.path); resp.StatusCode != tt.want {
+			t.Errorf("GET %s without client certificate = %d, want %d", tt.path, resp.StatusCode, tt.want)
+		}
+	}
+
+	// A certificate from another CA is refused in the handshake; the client
+	// would not even send it unless made to
+	other, otherKey, _, _ := issue(t, "other CA", 4, nil, nil, true)
+	_, _, strangerPEM, strangerKeyPEM := issue(t, "stranger", 5, other, otherKey, false)
+	strangerCert, err := tls.X509KeyPair(strangerPEM, strangerKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger := client()
+	stranger.Transport.(*http.Transport).TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &strangerCert, nil
+	}
+	if _, err := stranger.Get(srv.URL + "/healthz"); err == nil {
+		t.Error("Request with a certificate of another CA succeeded")
+	}
+
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := get(client(clientCert), "/example.com/a/@v/list")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET with client certificate = %d, want 200", resp.StatusCode)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Request used %s, want HTTP/2", resp.Proto)
+	}
+}
+
+func TestRedirectURL(t *testing.T) {
+	s := NewServerWithOptions("/data", "0.0.0.0", 8443, Options{TLSCert: "c", TLSKey: "k", RedirectHTTP: ":80"})
+	req := httptest.NewRequest("GET", "http://proxy.corp:80/example.com/a/@v/list?x=1", nil)
+	if got, want := s.redirectURL(req), "https://proxy.corp:8443/example.com/a/@v/list?x=1"; got != want {
+		t.Errorf("redirectURL = %q, want %q", got, want)
+	}
+}
+
+// userAuth authenticates the basic auth user name without a password.
+type userAuth struct{}
+
+func (userAuth) Authenticate(r *http.Request) string {
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+func TestHandleRequest_AuthAndACL(t *testing.T) {
+	root := t.TempDir()
+	atV := filepath.Join(root, "corp.example.com", "team", "lib", "@v")
+	os.MkdirAll(atV, 0755)
+	writeFile(t, filepath.Join(atV, "list"), []byte("v1.0.0\n"))
+	os.MkdirAll(filepath.Join(root, "vulndb", "index"), 0755)
+	writeFile(t, filepath.Join(root, "vulndb", "index", "db.json"), []byte(`{"modified":"2026-01-02T00:00:00Z"}`))
+
+	acl := &auth.ACL{Rules: []auth.ACLRule{{Prefix: "corp.example.com/team", Users: []string{"alice"}}}}
+	s := NewServerWithOptions(root, "localhost", 3000, Options{Auth: userAuth{}, ACL: acl})
+	fs := http.FileServer(http.Dir(root))
+
+	const list = "/corp.example.com/team/lib/@v/list"
+	tests := []struct {
+		user, path string
+		want       int
+	}{
+		{"", list, http.StatusUnauthorized},
+		{"bob", list, http.StatusForbidden},
+		{"alice", list, http.StatusOK},
+		// The vulnerability database is not limited to module prefixes
+		{"", "/vulndb/index/db.json", http.StatusUnauthorized},
+		{"alice", "/vulndb/index/db.json", http.StatusOK},
+		{"bob", "/vulndb/../corp.example.com/team/lib/@v/list", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, "")
+		}
+		rec := httptest.NewRecorder()
+		s.handleRequest(rec, req, fs)
+		if rec.Code != tt.want {
+			t.Errorf("user %q: %s = %d, want %d", tt.user, tt.path, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Error("401 response without WWW-Authenticate header")
+		}
+	}
+}
+
+func TestIsHidden(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/_quarantine/audit.log":      true,
+		"/.git/config":                true,
+		"/x/../_quarantine/a/@v/list": true,
+		"/example.com/a/@v/list":      false,
+		"/vulndb/index/db.json":       false,
+	} {
+		if got := isHidden(path); got != want {
+			t.Errorf("isHidden(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestHandleRequest_HidesQuarantine(t *testing.T) {
+	root := t.TempDir()
+	staged := filepath.Join(root, "_quarantine", "example.com", "a", "@v")
+	os.MkdirAll(staged, 0755)
+	writeFile(t, filepath.Join(staged, "v1.0.0.info"), []byte(`{"Version":"v1.0.0"}`))
+
+	s := NewServer(root, "localhost", 3000)
+	rec := httptest.NewRecorder()
+	s.handleRequest(rec, httptest.NewRequest("GET", "/_quarantine/example.com/a/@v/v1.0.0.info", nil), http.FileServer(http.Dir(root)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Quarantined version served with status %d, want 404", rec.Code)
+	}
+}
+
+func TestRequestModulePath(t *testing.T) {
+	for urlPath, want := range map[string]string{
+		"/github.com/!azure/go-autorest/@v/list":        "github.com/Azure/go-autorest",
+		"/golang.org/x/text/@v/v0.14.0.zip":             "golang.org/x/text",
+		"/golang.org/x/text/@latest":                    "golang.org/x/text",
+		"/vulndb/index/db.json":                         "vulndb/index/db.json",
+		"/api/v1/modules/github.com/Azure/lib/versions": "github.com/Azure/lib",
+	} {
+		if got := requestModulePath(urlPath); got != want {
+			t.Errorf("requestModulePath(%q) = %q, want %q", urlPath, got, want)
+		}
+	}
+}
+
+func TestProbes(t *testing.T) {
+	root := t.TempDir()
+	s := NewServerWithOptions(root, "localhost", 0, Options{Auth: userAuth{}})
+	h := s.Handler()
+
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+	// Probes answer without credentials
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", code)
+	}
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz = %d, want 200", code)
+	}
+
+	os.RemoveAll(root)
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz with missing storage root = %d, want 503", code)
+	}
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz with missing storage root = %d, want 200", code)
+	}
+}
+
+func TestServe_GracefulShutdown(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "example.com", "m", "@v"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "example.com", "m", "@v", "list"), []byte("v1.0.0\n"))
+	// Large enough that serving it blocks until the client reads
+	const zipSize = 32 << 20
+	writeFile(t, filepath.Join(root, "example.com", "m", "@v", "v1.0.0.zip"), make([]byte, zipSize))
+	s := NewServerWithOptions(root, "127.0.0.1", 0, Options{DrainDelay: 500 * time.Millisecond})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "http://" + ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.serve(ctx, ln) }()
+
+	resp, err := http.Get(addr + "/example.com/m/@v/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	// A download the client reads only after shutdown has started is in
+	// flight and must complete
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /example.com/m/@v/v1.0.0.zip HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	// During the drain delay readiness fails but requests are still served
+	deadline := time.Now().Add(400 * time.Millisecond)
+	for {
+		resp, err := http.Get(addr + "/readyz")
+		if err != nil {
+			t.Fatalf("readiness check during the drain delay failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("/readyz = %d during the drain delay, want 503", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Once new connections are refused, read the in-flight download
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			break
+		}
+		c.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("listener still open after the drain delay")
+		}
+	}
+	inflight, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("in-flight request failed: %v", err)
+	}
+	n, err := io.Copy(io.Discard, inflight.Body)
+	inflight.Body.Close()
+	if inflight.StatusCode != http.StatusOK || err != nil || n != zipSize {
+		t.Errorf("in-flight download = %d, %d bytes (%v), want 200 with %d bytes", inflight.StatusCode, n, err, zipSize)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve = %v, want nil after shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	if _, err := http.Get(addr + "/healthz"); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
+
+func TestEndpointType(t *testing.T) {
+	tests := map[string]string{
+		"/example.com/m/@v/list":                  endpointList,
+		"/example.com/m/@v/v1.0.0.info":           endpointInfo,
+		"/example.com/m/@v/v1.0.0.mod":            endpointMod,
+		"/example.com/m/@v/v1.0.0.zip":            endpointZip,
+		"/example.com/m/@latest":                  endpointLatest,
+		"/vulndb/index/db.json":                   endpointVulnDB,
+		"/":                                       endpointOther,
+		"/api/v1/modules/example.com/m/@v/v1.0.0": endpointUpload,
+		"/example.com/m/@v/v1.0.0.sbom":           endpointOther,
+	}
+	for path, want := range tests {
+		if got := endpointType(path); got != want {
+			t.Errorf("endpointType(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	root := t.TempDir()
+	atV := filepath.Join(root, "example.com", "m", "@v")
+	if err := os.MkdirAll(atV, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(atV, "v1.0.0.zip"), []byte("0123456789"))
+	h := NewServer(root, "localhost", 0).Handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+	get("/example.com/m/@v/v1.0.0.zip")
+	get("/example.com/m/@v/v2.0.0.zip")
+	get("/healthz")
+
+	body := get("/metrics").Body.String()
+	for _, want := range []string{
+		`gomodclone_http_requests_total{endpoint="zip",code="200"} 1`,
+		`gomodclone_http_requests_total{endpoint="zip",code="404"} 1`,
+		`gomodclone_http_request_duration_seconds_count{endpoint="zip"} 2`,
+		`gomodclone_storage_bytes 10`,
+		`gomodclone_storage_module_versions 1`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `endpoint="other"`) {
+		t.Errorf("probes were counted:\n%s", body)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	root := t.TempDir()
+	atV := filepath.Join(root, "github.com", "!azure", "sdk", "@v")
+	if err := os.MkdirAll(atV, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(atV, "v1.2.0.zip"), []byte("zipdata"))
+	file := filepath.Join(t.TempDir(), "access.log")
+	l, err := accesslog.Open(file, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewServerWithOptions(root, "localhost", 0, Options{
+		Auth:       userAuth{},
+		AccessLog:  l,
+		TrustProxy: true,
+	}).Handler()
+
+	req := httptest.NewRequest("GET", "/github.com/!azure/sdk/@v/v1.2.0.zip", nil)
+	req.SetBasicAuth("alice", "")
+	req.Header.Set("X-Forwarded-For", "192.168.0.1, 10.1.2.3")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/github.com/!azure/sdk/@v/list", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	l.Close()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d entries, want 2 (probes are not logged):\n%s", len(lines), data)
+	}
+	var e accesslog.Entry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	want := accesslog.Entry{
+		Time:       e.Time,
+		ClientIP:   "10.1.2.3",
+		User:       "alice",
+		Method:     "GET",
+		Path:       "/github.com/!azure/sdk/@v/v1.2.0.zip",
+		Module:     "github.com/Azure/sdk",
+		Version:    "v1.2.0",
+		Artifact:   endpointZip,
+		Status:     http.StatusOK,
+		Bytes:      7,
+		DurationMS: e.DurationMS,
+	}
+	if e != want {
+		t.Errorf("entry = %+v\nwant %+v", e, want)
+	}
+	e = accesslog.Entry{}
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != http.StatusUnauthorized || e.User != "" || e.Artifact != endpointList {
+		t.Errorf("unauthenticated entry = %+v", e)
+	}
+}
+
+func TestUI(t *testing.T) {
+	root := t.TempDir()
+	for _, m := range []string{"corp.example.com/team/lib", "github.com/pub/lib"} {
+		atV := filepath.Join(root, filepath.FromSlash(m), "@v")
+		if err := os.MkdirAll(atV, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(atV, "list"), []byte("v1.0.0\nv1.1.0\n"))
+		writeFile(t, filepath.Join(atV, "v1.1.0.info"), []byte(`{"Version":"v1.1.0","Time":"2024-03-04T05:06:07Z"}`))
+		writeFile(t, filepath.Join(atV, "v1.1.0.mod"), []byte("module "+m+"\n\nrequire golang.org/x/text v0.14.0\n"))
+		writeFile(t, filepath.Join(atV, "v1.1.0.zip"), make([]byte, 2048))
+		writeFile(t, filepath.Join(atV, "v1.1.0.license.json"), []byte(`{"licenses":["Apache-2.0"],"files":[{"name":"LICENSE","license":"Apache-2.0"}]}`))
+	}
+	acl := &auth.ACL{Rules: []auth.ACLRule{
+		{Prefix: "", Users: []string{auth.Everyone}},
+		{Prefix: "corp.example.com", Users: []string{"alice"}},
+	}}
+	h := NewServerWithOptions(root, "localhost", 0, Options{Auth: userAuth{}, ACL: acl}).Handler()
+
+	get := func(user, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetBasicAuth(user, "")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The module list only shows what the user may read
+	body := get("bob", "/").Body.String()
+	if !strings.Contains(body, "github.com/pub/lib") || strings.Contains(body, "corp.example.com") {
+		t.Errorf("bob's module list:\n%s", body)
+	}
+	if body := get("alice", "/?q=CORP").Body.String(); !strings.Contains(body, "corp.example.com/team/lib") || strings.Contains(body, "github.com/pub/lib") {
+		t.Errorf("alice's search results:\n%s", body)
+	}
+
+	rec := get("alice", "/ui/mod/corp.example.com/team/lib")
+	for _, want := range []string{"v1.1.0", "2024-03-04 05:06 UTC", "Apache-2.0", "2.0 KiB", "v1.0.0"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("module page missing %q:\n%s", want, rec.Body.String())
+		}
+	}
+	rec = get("alice", "/ui/mod/corp.example.com/team/lib@v1.1.0")
+	for _, want := range []string{"require golang.org/x/text v0.14.0", "go get corp.example.com/team/lib@v1.1.0", "http://example.com", "LICENSE"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("version page missing %q:\n%s", want, rec.Body.String())
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/ui/mod/corp.example.com/team/lib":        http.StatusForbidden,
+		"/ui/mod/corp.example.com/team/lib@v1.1.0": http.StatusForbidden,
+		"/ui/mod/github.com/pub/lib@v1.0.0":        http.StatusNotFound, // no zip
+		"/ui/mod/github.com/pub/lib@..%5C..%5Cx":   http.StatusNotFound,
+		"/ui/mod/_quarantine":                      http.StatusNotFound,
+		"/ui/static/style.css":                     http.StatusOK,
+	} {
+		if code := get("bob", path).Code; code != want {
+			t.Errorf("bob: %s = %d, want %d", path, code, want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	s := NewServerWithOptions("/data", "localhost", 0, Options{TrustProxy: true})
+	tests := []struct {
+		name string
+		fwd  []string
+		want string
+	}{
+		{"no header", nil, "192.0.2.1"},
+		{"proxy only", []string{"10.1.2.3"}, "10.1.2.3"},
+		{"spoofed entry", []string{"127.0.0.1, 10.1.2.3"}, "10.1.2.3"},
+		{"spoofed header line", []string{"127.0.0.1", "10.1.2.3"}, "10.1.2.3"},
+		{"empty entry", []string{"127.0.0.1, "}, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		for _, v := range tt.fwd {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		if got := s.clientIP(req); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB"} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestDocsAndSource(t *testing.T) {
+	root := t.TempDir()
+	atV := filepath.Join(root, "corp.example.com", "greet", "@v")
+	if err := os.MkdirAll(atV, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(atV, "v1.0.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"go.mod":       "module corp.example.com/greet\n",
+		"greet.go":     "// Package greet says hello, see [corp.example.com/greet/loud.Shout].\npackage greet\n\n// Hello returns a greeting.\nfunc Hello() string { return \"<hi>\" }\n",
+		"loud/loud.go": "package loud\n\n// Shout shouts.\nfunc Shout() {}\n",
+	} {
+		w, _ := zw.Create("corp.example.com/greet@v1.0.0/" + name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	f.Close()
+
+	acl := &auth.ACL{Rules: []auth.ACLRule{{Prefix: "corp.example.com", Users: []string{"alice"}}}}
+	s := NewServerWithOptions(root, "localhost", 0, Options{Auth: userAuth{}, ACL: acl})
+	h := s.Handler()
+	get := func(user, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetBasicAuth(user, "")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for path, wants := range map[string][]string{
+		"/ui/doc/corp.example.com/greet@v1.0.0": {
+			"package greet",
+			`<a href="/ui/doc/corp.example.com/greet@v1.0.0/loud#Shout">corp.example.com/greet/loud.Shout</a>`,
+			"func Hello() string</pre>",
+			`href="/ui/doc/corp.example.com/greet@v1.0.0/loud">loud</a>`,
+		},
+		"/ui/doc/corp.example.com/greet@v1.0.0/loud":     {"package loud", "func Shout()"},
+		"/ui/src/corp.example.com/greet@v1.0.0":          {`greet@v1.0.0/loud">loud/</a>`, `greet@v1.0.0/go.mod">go.mod</a>`},
+		"/ui/src/corp.example.com/greet@v1.0.0/greet.go": {`return &#34;&lt;hi&gt;&#34;`},
+	} {
+		rec := get("alice", path)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s = %d, want 200", path, rec.Code)
+			continue
+		}
+		for _, want := range wants {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("%s missing %q:\n%s", path, want, rec.Body.String())
+			}
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/ui/doc/corp.example.com/greet@v2.0.0":          http.StatusNotFound,
+		"/ui/doc/corp.example.com/greet@v1.0.0/missing":  http.StatusNotFound,
+		"/ui/src/corp.example.com/greet@v1.0.0/missing":  http.StatusNotFound,
+		"/ui/src/corp.example.com/greet@v1.0.0/greet.go": http.StatusOK,
+	} {
+		if code := get("alice", path).Code; code != want {
+			t.Errorf("alice: %s = %d, want %d", path, code, want)
+		}
+	}
+	if code := get("bob", "/ui/src/corp.example.com/greet@v1.0.0/greet.go").Code; code != http.StatusForbidden {
+		t.Errorf("bob: source = %d, want 403", code)
+	}
+
+	// The package list is parsed once per version and then reused
+	if pkgs := s.packages.pkgs["corp.example.com/greet@v1.0.0"]; len(pkgs) != 2 {
+		t.Errorf("Cached packages = %+v, want greet and loud", pkgs)
+	}
+	s.packages.pkgs["corp.example.com/greet@v1.0.0"] = []moddoc.Package{{ImportPath: "corp.example.com/greet/cached", Dir: "cached", Name: "cached"}}
+	if body := get("alice", "/ui/doc/corp.example.com/greet@v1.0.0").Body.String(); !strings.Contains(body, "greet@v1.0.0/cached") {
+		t.Errorf("Package list not taken from the cache:\n%s", body)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	root := t.TempDir()
+	for _, m := range []string{"corp.example.com/team/lib", "github.com/a/golib", "github.com/pub/lib", "github.com/pub/libtool", "_quarantine/github.com/new/mod"} {
+		atV := filepath.Join(root, filepath.FromSlash(m), "@v")
+		if err := os.MkdirAll(atV, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(atV, "list"), []byte("v1.0.0\n"))
+	}
+	atV := filepath.Join(root, "github.com", "pub", "lib", "@v")
+	writeFile(t, filepath.Join(atV, "v1.0.0.info"), []byte(`{"Version":"v1.0.0","Time":"2024-03-04T05:06:07Z"}`))
+	writeFile(t, filepath.Join(atV, "v1.0.0.mod"), []byte("module github.com/pub/lib\n"))
+	writeFile(t, filepath.Join(atV, "v1.0.0.license.json"), []byte(`{"licenses":["MIT"]}`))
+	f, err := os.Create(filepath.Join(atV, "v1.0.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("github.com/pub/lib@v1.0.0/go.mod")
+	w.Write([]byte("module github.com/pub/lib\n"))
+	zw.Close()
+	f.Close()
+	zipHash, err := modzip.HashZip(filepath.Join(atV, "v1.0.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acl := &auth.ACL{Rules: []auth.ACLRule{
+		{Prefix: "", Users: []string{auth.Everyone}},
+		{Prefix: "corp.example.com", Users: []string{"alice"}},
+	}}
+	h := NewServerWithOptions(root, "localhost", 0, Options{Auth: userAuth{}, ACL: acl}).Handler()
+	get := func(user, path string, v interface{}) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetBasicAuth(user, "")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if v != nil && rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+				t.Fatalf("%s: %v\n%s", path, err, rec.Body.String())
+			}
+		}
+		return rec.Code
+	}
+	paths := func(modules []apiModule) string {
+		var out []string
+		for _, m := range modules {
+			out = append(out, m.Path)
+		}
+		return strings.Join(out, " ")
+	}
+
+	// Paging through the modules bob may read
+	var list apiModuleList
+	get("bob", "/api/v1/modules?prefix=github.com/&per_page=2", &list)
+	if list.Total != 3 || list.NextPage != 2 || paths(list.Modules) != "github.com/a/golib github.com/pub/lib" {
+		t.Errorf("page 1 = %+v", list)
+	}
+	list = apiModuleList{}
+	get("bob", "/api/v1/modules?prefix=github.com/&per_page=2&page=2", &list)
+	if list.NextPage != 0 || paths(list.Modules) != "github.com/pub/libtool" {
+		t.Errorf("page 2 = %+v", list)
+	}
+	for _, page := range []string{"3", "92233720368547760", "9223372036854775807"} {
+		list = apiModuleList{}
+		if code := get("bob", "/api/v1/modules?prefix=github.com/&per_page=1000&page="+page, &list); code != http.StatusOK || len(list.Modules) != 0 || list.NextPage != 0 {
+			t.Errorf("page %s = %d %+v, want an empty page", page, code, list)
+		}
+	}
+	list = apiModuleList{}
+	get("alice", "/api/v1/modules", &list)
+	if list.Total != 4 || list.Modules[0].Path != "corp.example.com/team/lib" {
+		t.Errorf("alice's modules = %+v", list)
+	}
+
+	var versions apiVersionList
+	get("bob", "/api/v1/modules/github.com/pub/lib/versions", &versions)
+	if len(versions.Versions) != 1 {
+		t.Fatalf("versions = %+v", versions)
+	}
+	v := versions.Versions[0]
+	if v.Version != "v1.0.0" || v.Time == nil || v.ZipHash != zipHash || !strings.HasPrefix(v.GoModHash, "h1:") || len(v.Licenses) != 1 {
+		t.Errorf("version = %+v", v)
+	}
+
+	var stats apiStats
+	get("bob", "/api/v1/stats", &stats)
+	if stats.Modules != 4 || stats.Versions != 1 || stats.StorageBytes == 0 || stats.PendingApproval != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	var results apiSearchResults
+	get("bob", "/api/v1/search?q=LIB", &results)
+	if got := paths(results.Results); got != "github.com/pub/lib github.com/pub/libtool github.com/a/golib" {
+		t.Errorf("search results = %s", got)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/modules/corp.example.com/team/lib/versions": http.StatusForbidden,
+		"/api/v1/modules/github.com/none/versions":           http.StatusNotFound,
+		"/api/v1/modules/_quarantine/versions":               http.StatusNotFound,
+		"/api/v1/modules?page=0":                             http.StatusBadRequest,
+		"/api/v1/search":                                     http.StatusBadRequest,
+		"/api/v1/unknown":                                    http.StatusNotFound,
+	} {
+		if code := get("bob", path, nil); code != want {
+			t.Errorf("bob: %s = %d, want %d", path, code, want)
+		}
+	}
+}
+
+// moduleZip returns a module zip with the given files.
+func moduleZip(t *testing.T, prefix string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(prefix + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUpload(t *testing.T) {
+	root := t.TempDir()
+	acl := &auth.ACL{Rules: []auth.ACLRule{{Prefix: "", Users: []string{auth.Everyone}}}}
+	uploadACL := &auth.ACL{Rules: []auth.ACLRule{{Prefix: "corp.example.com", Users: []string{"alice"}}}}
+	h := NewServerWithOptions(root, "localhost", 0, Options{
+		Auth:      userAuth{},
+		ACL:       acl,
+		Uploads:   packer.NewPacker(root),
+		UploadACL: uploadACL,
+	}).Handler()
+
+	put := func(h http.Handler, user, urlPath string, files map[string][]byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for name, data := range files {
+			w, _ := mw.CreateFormFile(name, name)
+			w.Write(data)
+		}
+		mw.Close()
+		req := httptest.NewRequest("PUT", urlPath, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetBasicAuth(user, "")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	goMod := "module corp.example.com/lib\n\ngo 1.21\n"
+	libZip := moduleZip(t, "corp.example.com/lib@v1.0.0/", map[string]string{"go.mod": goMod, "lib.go": "package lib\n"})
+
+	rec := put(h, "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.0.0", map[string][]byte{"zip": libZip})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload = %d: %s", rec.Code, rec.Body.String())
+	}
+	var result apiUploadResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.Status != uploadPublished || !strings.HasPrefix(result.ZipHash, "h1:") {
+		t.Errorf("upload result = %+v, %v", result, err)
+	}
+	atV := filepath.Join(root, "corp.example.com", "lib", "@v")
+	if data, err := os.ReadFile(filepath.Join(atV, "v1.0.0.mod")); err != nil || string(data) != goMod {
+		t.Errorf("v1.0.0.mod = %q, %v", data, err)
+	}
+	for _, name := range []string{"list", "v1.0.0.info", "v1.0.0.zip", "v1.0.0.zipcheck.json"} {
+		if _, err := os.Stat(filepath.Join(atV, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name, user, path string
+		files            map[string][]byte
+		want             int
+	}{
+		{"overwrite", "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.0.0", map[string][]byte{"zip": libZip}, http.StatusConflict},
+		{"no upload permission", "bob", "/api/v1/modules/corp.example.com/lib/@v/v1.0.1", map[string][]byte{"zip": libZip}, http.StatusForbidden},
+		{"outside upload ACL", "alice", "/api/v1/modules/github.com/pub/lib/@v/v1.0.0", map[string][]byte{"zip": libZip}, http.StatusForbidden},
+		{"missing zip", "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.0.1", map[string][]byte{"mod": []byte(goMod)}, http.StatusBadRequest},
+		{"mod differs", "alice", "/api/v1/modules/corp.example.com/other/@v/v1.0.0", map[string][]byte{
+			"zip": moduleZip(t, "corp.example.com/other@v1.0.0/", map[string]string{"go.mod": "module corp.example.com/other\n"}),
+			"mod": []byte("module corp.example.com/other\n\ngo 1.21\n"),
+		}, http.StatusBadRequest},
+		{"wrong module", "alice", "/api/v1/modules/corp.example.com/other/@v/v1.0.0", map[string][]byte{
+			"zip": moduleZip(t, "corp.example.com/other@v1.0.0/", map[string]string{"go.mod": goMod}),
+		}, http.StatusBadRequest},
+		{"wrong info", "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.0.1", map[string][]byte{
+			"zip":  moduleZip(t, "corp.example.com/lib@v1.0.1/", map[string]string{"go.mod": goMod}),
+			"info": []byte(`{"Version":"v1.0.0"}`),
+		}, http.StatusBadRequest},
+		{"major version", "alice", "/api/v1/modules/corp.example.com/lib/@v/v2.0.0", map[string][]byte{"zip": libZip}, http.StatusBadRequest},
+		{"not canonical", "alice", "/api/v1/modules/corp.example.com/lib/@v/latest", map[string][]byte{"zip": libZip}, http.StatusBadRequest},
+		{"invalid zip", "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.0.2", map[string][]byte{
+			"zip": moduleZip(t, "corp.example.com/lib@v1.0.2/", map[string]string{"go.mod": goMod, "vendor/x/x.go": "package x\n"}),
+		}, http.StatusUnprocessableEntity},
+	} {
+		if rec := put(h, tt.user, tt.path, tt.files); rec.Code != tt.want {
+			t.Errorf("%s: got %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(atV, "v1.0.2.zip")); err == nil {
+		t.Error("invalid zip was published")
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/modules/corp.example.com/lib/@v/v1.0.0", nil)
+	req.SetBasicAuth("alice", "")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "PUT" {
+		t.Errorf("GET upload URL = %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+
+	// With quarantine, uploads wait for approval
+	staging := NewServerWithOptions(root, "localhost", 0, Options{
+		Auth:      userAuth{},
+		ACL:       acl,
+		Uploads:   packer.NewPackerWithOptions(root, packer.Options{Quarantine: true}),
+		UploadACL: uploadACL,
+	}).Handler()
+	v110 := moduleZip(t, "corp.example.com/lib@v1.1.0/", map[string]string{"go.mod": goMod})
+	if rec := put(staging, "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.1.0", map[string][]byte{"zip": v110}); rec.Code != http.StatusAccepted {
+		t.Errorf("staged upload = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := put(h, "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.1.0", map[string][]byte{"zip": v110}); rec.Code != http.StatusConflict {
+		t.Errorf("upload of staged version = %d, want 409", rec.Code)
+	}
+
+	// Uploads may take longer than the read and write timeouts of other
+	// requests, up to the upload timeout or without limit if it is negative
+	for uploadTimeout, version := range map[time.Duration]string{0: "v1.3.0", -1: "v1.4.0"} {
+		s := NewServerWithOptions(root, "localhost", 0, Options{
+			Auth:          userAuth{},
+			ACL:           acl,
+			Uploads:       packer.NewPacker(root),
+			UploadACL:     uploadACL,
+			ReadTimeout:   200 * time.Millisecond,
+			WriteTimeout:  200 * time.Millisecond,
+			UploadTimeout: uploadTimeout,
+		})
+		slow := httptest.NewUnstartedServer(nil)
+		slow.Config = s.httpServer(s.Handler())
+		slow.Start()
+		defer slow.Close()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		w, _ := mw.CreateFormFile("zip", version+".zip")
+		w.Write(moduleZip(t, "corp.example.com/lib@"+version+"/", map[string]string{"go.mod": goMod}))
+		mw.Close()
+		pr, pw := io.Pipe()
+		go func() {
+			half := body.Len() / 2
+			pw.Write(body.Bytes()[:half])
+			time.Sleep(500 * time.Millisecond)
+			pw.Write(body.Bytes()[half:])
+			pw.Close()
+		}()
+		req, _ = http.NewRequest("PUT", slow.URL+"/api/v1/modules/corp.example.com/lib/@v/"+version, pr)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetBasicAuth("alice", "")
+		if resp, err := http.DefaultClient.Do(req); err != nil {
+			t.Errorf("slow upload with --upload-timeout %s failed: %v", uploadTimeout, err)
+		} else {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusCreated {
+				t.Errorf("slow upload with --upload-timeout %s = %d, want 201", uploadTimeout, resp.StatusCode)
+			}
+		}
+	}
+
+	// Without an upload packer the API is read-only
+	readOnly := NewServerWithOptions(root, "localhost", 0, Options{Auth: userAuth{}, ACL: acl}).Handler()
+	if rec := put(readOnly, "alice", "/api/v1/modules/corp.example.com/lib/@v/v1.2.0", map[string][]byte{"zip": libZip}); rec.Code != http.StatusForbidden {
+		t.Errorf("upload without --upload-acl = %d, want 403", rec.Code)
+	}
+}
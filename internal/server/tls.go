@@ -0,0 +1,160 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/log"
+)
+
+// certPollInterval is how often the certificate files are checked for
+// changes, in addition to reloading on SIGHUP.
+const certPollInterval = 30 * time.Second
+
+// certReloader serves the server certificate and the client CA pool from
+// files, reloading them when they change. A failed reload keeps the
+// previous certificates, so a half-written renewal never takes the server
+// down.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+func newCertReloader(certFile, keyFile, caFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads the certificate, key and client CA files.
+func (r *certReloader) load() error {
+	modTimes := r.statFiles()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		data, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in client CA %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) statFiles() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+		if info, err := os.Stat(f); err == nil {
+			modTimes[f] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// changed reports whether any file was modified since the last load.
+func (r *certReloader) changed() bool {
+	current := r.statFiles()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for f, t := range current {
+		if !t.Equal(r.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *certReloader) reload(why string) {
+	if err := r.load(); err != nil {
+		log.Error("Certificate reload (%s) failed, keeping the previous certificate: %v", why, err)
+		return
+	}
+	log.Info("Reloaded TLS certificates (%s)", why)
+}
+
+// watch reloads the certificates on SIGHUP or when the files change, until
+// stop is closed.
+func (r *certReloader) watch(stop <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(certPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-hup:
+			r.reload("SIGHUP")
+		case <-ticker.C:
+			if r.changed() {
+				r.reload("files changed")
+			}
+		}
+	}
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// tlsConfig returns the server's TLS configuration. With a client CA the
+// certificates clients present must be signed by it; clients without one
+// may still connect for the probes, and Handler refuses them everything
+// else. The configuration for a
+// client is a copy of the base one, so it offers HTTP/2 like the base does;
+// the protocols are listed here because net/http only adds them to its own
+// copy of the base.
+func (r *certReloader) tlsConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if r.caFile == "" {
+		return base
+	}
+	clientAuth := base.Clone()
+	clientAuth.ClientAuth = tls.VerifyClientCertIfGiven
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		pool := r.clientCA
+		r.mu.RUnlock()
+		config := clientAuth.Clone()
+		config.ClientCAs = pool
+		return config, nil
+	}
+	return base
+}
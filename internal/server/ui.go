@@ -0,0 +1,289 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/license"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/storage"
+	"github.com/example/go-mod-clone/internal/vulndb"
+)
+
+// URL prefixes of the web UI. Module paths need a dot in their first
+// element, so these never collide with proxy requests.
+const (
+	uiPrefix       = "/ui/"
+	uiStaticPrefix = "/ui/static/"
+	uiModulePrefix = "/ui/mod/"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+var uiTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"date":       formatDate,
+	"size":       formatSize,
+	"modulePage": modulePage,
+	"download":   download,
+	"docPage":    docPage,
+	"srcPage":    srcPage,
+}).ParseFS(uiFiles, "ui/*.html"))
+
+// uiModule is a row of the module list.
+type uiModule struct {
+	Path     string
+	Versions int
+	Latest   string
+	Time     time.Time // set by readTimes
+
+	dir string
+}
+
+// uiVersion is a module version with the metadata stored next to its zip.
+type uiVersion struct {
+	Version  string
+	Time     time.Time
+	Size     int64
+	Licenses []string
+	License  *license.Report
+	GoMod    string
+}
+
+// uiPage is the data of every page.
+type uiPage struct {
+	Title   string
+	Proxy   string // GOPROXY URL of this server as seen by the client
+	User    string
+	Query   string
+	Modules []uiModule
+	VulnDB  bool
+
+	Module   string
+	Versions []uiVersion
+	Version  *uiVersion
+	Source   *uiSource
+}
+
+// handleUI serves the pages of the web UI.
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request, user string) {
+	urlPath := r.URL.Path
+	switch {
+	case strings.HasPrefix(urlPath, uiStaticPrefix):
+		static, _ := fs.Sub(uiFiles, "ui/static")
+		http.StripPrefix(uiStaticPrefix, http.FileServer(http.FS(static))).ServeHTTP(w, r)
+	case strings.HasPrefix(urlPath, uiDocPrefix):
+		s.docUI(w, r, user)
+	case strings.HasPrefix(urlPath, uiSrcPrefix):
+		s.srcUI(w, r, user)
+	case strings.HasPrefix(urlPath, uiModulePrefix):
+		modPath, version, _ := strings.Cut(strings.TrimPrefix(urlPath, uiModulePrefix), "@")
+		if version == "" {
+			s.moduleUI(w, r, user, modPath)
+		} else {
+			s.versionUI(w, r, user, modPath, version)
+		}
+	case urlPath == "/" || urlPath == "":
+		s.indexUI(w, r, user)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) newPage(r *http.Request, user, title string) *uiPage {
+	return &uiPage{
+		Title: title,
+		Proxy: s.scheme() + "://" + r.Host,
+		User:  user,
+	}
+}
+
+// indexUI lists the modules the user may read, filtered by the q parameter.
+func (s *Server) indexUI(w http.ResponseWriter, r *http.Request, user string) {
+	page := s.newPage(r, user, "Modules")
+	page.Query = strings.TrimSpace(r.URL.Query().Get("q"))
+	_, err := os.Stat(vulndb.Dir(s.storageRoot))
+	page.VulnDB = err == nil
+
+	query := strings.ToLower(page.Query)
+	modules, err := s.listModules(user, func(modPath string) bool {
+		return strings.Contains(strings.ToLower(modPath), query)
+	})
+	if err != nil {
+		log.Error("Failed to list modules: %v", err)
+		http.Error(w, "failed to list modules", http.StatusInternalServerError)
+		return
+	}
+	readTimes(modules)
+	page.Modules = modules
+	s.render(w, "index.html", page)
+}
+
+// listModules returns the modules the user may read whose path satisfies
+// match, sorted by path.
+func (s *Server) listModules(user string, match func(modPath string) bool) ([]uiModule, error) {
+	modules, err := storage.ListModules(s.storageRoot)
+	if err != nil {
+		return nil, err
+	}
+	var rows []uiModule
+	for _, m := range modules {
+		if !s.readable(user, m.Path) || !match(m.Path) {
+			continue
+		}
+		row := uiModule{Path: m.Path, Versions: len(m.Versions), dir: m.Dir}
+		if len(m.Versions) > 0 {
+			row.Latest = m.Versions[len(m.Versions)-1]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readTimes sets the release time of each module's latest version.
+func readTimes(modules []uiModule) {
+	for i, m := range modules {
+		if m.Latest != "" {
+			modules[i].Time = readInfoTime(storage.VersionFile(m.dir, m.Latest, ".info"))
+		}
+	}
+}
+
+// moduleUI lists the versions of a module, newest first.
+func (s *Server) moduleUI(w http.ResponseWriter, r *http.Request, user, modPath string) {
+	atVDir, ok := s.moduleDir(user, modPath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	page := s.newPage(r, user, modPath)
+	page.Module = modPath
+	versions := storage.ReadList(atVDir)
+	for i := len(versions) - 1; i >= 0; i-- {
+		page.Versions = append(page.Versions, readVersion(atVDir, versions[i], false))
+	}
+	s.render(w, "module.html", page)
+}
+
+// versionUI shows one module version with its go.mod and license.
+func (s *Server) versionUI(w http.ResponseWriter, r *http.Request, user, modPath, version string) {
+	atVDir, ok := s.moduleDir(user, modPath)
+	if !ok || strings.ContainsAny(version, `/\`) {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := os.Stat(storage.VersionFile(atVDir, version, ".zip")); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	v := readVersion(atVDir, version, true)
+	page := s.newPage(r, user, modPath+"@"+version)
+	page.Module = modPath
+	page.Version = &v
+	s.render(w, "version.html", page)
+}
+
+// moduleDir returns the @v directory of a module the user may read.
+func (s *Server) moduleDir(user, modPath string) (string, bool) {
+	clean := strings.Trim(path.Clean("/"+modPath), "/")
+	if clean == "" || clean != modPath || isHidden(clean) || !s.readable(user, clean) {
+		return "", false
+	}
+	atVDir := filepath.Join(s.storageRoot, filepath.FromSlash(clean), "@v")
+	if info, err := os.Stat(atVDir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return atVDir, true
+}
+
+// readable reports whether the ACL lets user read a module.
+func (s *Server) readable(user, modPath string) bool {
+	return s.opts.Auth == nil || s.opts.ACL == nil || s.opts.ACL.Allowed(user, modPath)
+}
+
+// readVersion collects what is known about a version. The go.mod file is
+// only read for the version page.
+func readVersion(atVDir, version string, withGoMod bool) uiVersion {
+	v := uiVersion{
+		Version: version,
+		Time:    readInfoTime(storage.VersionFile(atVDir, version, ".info")),
+	}
+	if info, err := os.Stat(storage.VersionFile(atVDir, version, ".zip")); err == nil {
+		v.Size = info.Size()
+	}
+	if report, err := license.ReadMetadata(storage.VersionFile(atVDir, version, license.MetadataExt)); err == nil {
+		v.License = report
+		v.Licenses = report.Licenses
+	}
+	if withGoMod {
+		if data, err := os.ReadFile(storage.VersionFile(atVDir, version, ".mod")); err == nil {
+			v.GoMod = string(data)
+		}
+	}
+	return v
+}
+
+// readInfoTime returns the time in a .info file, or the zero time.
+func readInfoTime(file string) time.Time {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return time.Time{}
+	}
+	var info struct{ Time time.Time }
+	if json.Unmarshal(data, &info) != nil {
+		return time.Time{}
+	}
+	return info.Time
+}
+
+func (s *Server) render(w http.ResponseWriter, name string, page *uiPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplates.ExecuteTemplate(w, name, page); err != nil {
+		log.Error("Failed to render %s: %v", name, err)
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// modulePage returns the UI URL of a module or, with a version, of the
+// module version.
+func modulePage(modPath string, version ...string) string {
+	u := uiModulePrefix + modPath
+	if len(version) > 0 && version[0] != "" {
+		u += "@" + version[0]
+	}
+	return u
+}
+
+// download returns the proxy URL of a version's file with the given
+// extension.
+func download(modPath, version, ext string) string {
+	return "/" + modPath + "/@v/" + version + ext
+}
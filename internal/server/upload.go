@@ -0,0 +1,297 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/modzip"
+	"github.com/example/go-mod-clone/internal/policy"
+	"github.com/example/go-mod-clone/internal/quarantine"
+)
+
+// MaxUploadSize bounds the request body of an upload: a module zip of the
+// largest size the go command accepts, its go.mod and some slack for the
+// .info file and the multipart framing.
+const MaxUploadSize = modzip.MaxZipFile + modzip.MaxGoMod + 1<<20
+
+// Outcomes of an upload.
+const (
+	uploadPublished = "published"
+	uploadPending   = "pending_approval"
+)
+
+// apiUploadResult is the response of a successful upload.
+type apiUploadResult struct {
+	Path      string `json:"path"`
+	Version   string `json:"version"`
+	Status    string `json:"status"`
+	ZipHash   string `json:"zip_hash"`
+	GoModHash string `json:"go_mod_hash"`
+}
+
+// errUpload is an upload the client got wrong; its message is returned with
+// a 400 response.
+type errUpload string
+
+func (e errUpload) Error() string { return string(e) }
+
+// apiUpload publishes a module version sent as a multipart form with a
+// "zip" file and optional "mod" and "info" files. A missing .mod is taken
+// from the zip's go.mod and a missing .info is created with the current
+// time. The version goes through the same checks as prefilled ones, and
+// existing versions are never replaced.
+func (s *Server) apiUpload(w http.ResponseWriter, r *http.Request, user, modPath, version string) {
+	if s.opts.Uploads == nil {
+		apiError(w, http.StatusForbidden, "uploads are disabled")
+		return
+	}
+	if s.opts.Auth == nil || s.opts.UploadACL == nil || !s.opts.UploadACL.Allowed(user, modPath) {
+		log.Debug("Denied %s upload of %s@%s", user, modPath, version)
+		apiError(w, http.StatusForbidden, "not allowed to publish "+modPath)
+		return
+	}
+	if err := checkUploadTarget(modPath, version); err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.opts.Policy != nil {
+		violations := s.opts.Policy.Evaluate(policy.Subject{Path: modPath, Version: version})
+		for _, v := range violations {
+			log.Warn("Policy violation: %s", v)
+		}
+		if policy.Blocking(violations) {
+			apiError(w, http.StatusUnprocessableEntity, "blocked by policy: "+violations[0].Reason)
+			return
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "go-mod-clone-upload-")
+	if err != nil {
+		log.Error("Failed to create upload directory: %v", err)
+		apiError(w, http.StatusInternalServerError, "failed to store upload")
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	// A zip of up to MaxUploadSize takes longer to send than the read
+	// timeout of other requests allows; the response is only written once
+	// the body is in, so the write deadline moves along. A negative upload
+	// timeout clears both deadlines
+	rc := http.NewResponseController(w)
+	var deadline, writeDeadline time.Time
+	if s.opts.UploadTimeout > 0 {
+		deadline = time.Now().Add(s.opts.UploadTimeout)
+		writeDeadline = deadline.Add(s.opts.WriteTimeout)
+	}
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		log.Debug("Cannot extend the read deadline of an upload: %v", err)
+	}
+	if s.opts.WriteTimeout > 0 {
+		rc.SetWriteDeadline(writeDeadline)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	mod, err := receiveUpload(r, tmp, modPath, version)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		apiError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload larger than %d bytes", MaxUploadSize))
+		return
+	case err != nil:
+		var bad errUpload
+		if errors.As(err, &bad) {
+			apiError(w, http.StatusBadRequest, bad.Error())
+		} else {
+			log.Error("Failed to receive upload of %s@%s: %v", modPath, version, err)
+			apiError(w, http.StatusInternalServerError, "failed to store upload")
+		}
+		return
+	}
+
+	// Checking for an existing version and packing must not interleave
+	// with another upload of the same version
+	s.uploadMu.Lock()
+	defer s.uploadMu.Unlock()
+	published := filepath.Join(s.storageRoot, filepath.FromSlash(modPath), "@v", version+".zip")
+	staged := filepath.Join(quarantine.AtVDir(s.storageRoot, modPath), version+".zip")
+	for _, existing := range []string{published, staged} {
+		if _, err := os.Stat(existing); err == nil {
+			apiError(w, http.StatusConflict, modPath+"@"+version+" already exists")
+			return
+		}
+	}
+	if err := s.opts.Uploads.Pack(mod); err != nil {
+		log.Warn("Rejected upload of %s@%s by %s: %v", modPath, version, user, err)
+		apiError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	result := apiUploadResult{Path: modPath, Version: version, Status: uploadPublished}
+	code := http.StatusCreated
+	if _, err := os.Stat(published); err != nil {
+		result.Status = uploadPending
+		code = http.StatusAccepted
+	}
+	result.ZipHash, _ = modzip.HashZip(mod.ZipFile)
+	result.GoModHash, _ = modzip.HashGoMod(mod.ModFile)
+	log.Info("Uploaded %s@%s by %s (%s)", modPath, version, user, result.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(result)
+}
+
+// checkUploadTarget rejects module paths and versions that cannot be
+// published: tool state, unclean paths and non-canonical versions.
+func checkUploadTarget(modPath, version string) error {
+	clean := strings.Trim(path.Clean("/"+modPath), "/")
+	first, _, _ := strings.Cut(clean, "/")
+	switch {
+	case clean != modPath || isHidden(clean) || strings.ContainsAny(modPath, `\@`):
+		return fmt.Errorf("invalid module path %q", modPath)
+	case !strings.Contains(first, "."):
+		return fmt.Errorf("invalid module path %q: the first element needs a dot", modPath)
+	case !gomod.IsCanonicalVersion(version) || strings.Contains(version, "/"):
+		return fmt.Errorf("invalid version %q: need a canonical semantic version such as v1.2.3", version)
+	}
+	return gomod.CheckPathMajor(modPath, version)
+}
+
+// receiveUpload writes the files of an upload to dir and returns the module
+// to pack.
+func receiveUpload(r *http.Request, dir, modPath, version string) (gomod.Module, error) {
+	mod := gomod.Module{Path: modPath, Version: version}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return mod, errUpload("expected a multipart/form-data body with zip, mod and info files")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return mod, err
+			}
+			return mod, errUpload("malformed multipart body")
+		}
+		var target *string
+		switch part.FormName() {
+		case "zip":
+			target = &mod.ZipFile
+		case "mod":
+			target = &mod.ModFile
+		case "info":
+			target = &mod.InfoFile
+		default:
+			part.Close()
+			continue
+		}
+		if *target != "" {
+			return mod, errUpload("duplicate " + part.FormName() + " file")
+		}
+		if *target, err = savePart(part, filepath.Join(dir, version+"."+part.FormName())); err != nil {
+			return mod, err
+		}
+	}
+	if mod.ZipFile == "" {
+		return mod, errUpload("missing zip file")
+	}
+
+	// The .mod file must be the zip's go.mod, or go.sum checks fail
+	goMod, err := zipGoMod(mod.ZipFile, modPath, version)
+	if err != nil {
+		return mod, err
+	}
+	if mod.ModFile == "" {
+		mod.ModFile = filepath.Join(dir, version+".mod")
+		if err := os.WriteFile(mod.ModFile, goMod, 0644); err != nil {
+			return mod, err
+		}
+	} else if data, err := os.ReadFile(mod.ModFile); err != nil {
+		return mod, err
+	} else if !bytes.Equal(data, goMod) {
+		return mod, errUpload("mod file differs from the go.mod in the zip")
+	}
+	if got := gomod.ModulePath(string(goMod)); got != modPath {
+		return mod, errUpload(fmt.Sprintf("go.mod declares module %q, not %q", got, modPath))
+	}
+
+	if mod.InfoFile == "" {
+		mod.InfoFile = filepath.Join(dir, version+".info")
+		data, _ := json.Marshal(struct {
+			Version string
+			Time    time.Time
+		}{version, time.Now().UTC().Truncate(time.Second)})
+		if err := os.WriteFile(mod.InfoFile, data, 0644); err != nil {
+			return mod, err
+		}
+	}
+	data, err := os.ReadFile(mod.InfoFile)
+	if err != nil {
+		return mod, err
+	}
+	var info struct{ Version string }
+	if json.Unmarshal(data, &info) != nil || info.Version != version {
+		return mod, errUpload(fmt.Sprintf("info file must be JSON with \"Version\": %q", version))
+	}
+	return mod, nil
+}
+
+func savePart(part *multipart.Part, file string) (string, error) {
+	defer part.Close()
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, part); err != nil {
+		f.Close()
+		return "", err
+	}
+	return file, f.Close()
+}
+
+// zipGoMod returns the go.mod at the root of a module zip. Like the go
+// command, a module without one gets a go.mod with just the module
+// directive.
+func zipGoMod(zipFile, modPath, version string) ([]byte, error) {
+	zr, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return nil, errUpload("invalid zip file: " + err.Error())
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != modPath+"@"+version+"/go.mod" {
+			continue
+		}
+		if f.UncompressedSize64 > modzip.MaxGoMod {
+			return nil, errUpload("go.mod too large")
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, errUpload("invalid zip file: " + err.Error())
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, modzip.MaxGoMod))
+		if err != nil {
+			return nil, errUpload("invalid zip file: " + err.Error())
+		}
+		return data, nil
+	}
+	return []byte(fmt.Sprintf("module %s\n", modPath)), nil
+}
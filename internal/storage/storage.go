@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+)
+
+// Module is a module path in a storage root together with the versions
+// listed in its @v/list file.
+type Module struct {
+	Path     string
+	Versions []string // sorted by semantic version
+	Dir      string   // the module's @v directory
+}
+
+// ListModules walks root and returns every module that has an @v directory,
+// sorted by path. Top-level entries whose name starts with "." or "_" hold
+// tool state, not modules, and are skipped.
+func ListModules(root string) ([]Module, error) {
+	var modules []Module
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		name := d.Name()
+		if filepath.Dir(path) == root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		if name != "@v" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return nil
+		}
+		modules = append(modules, Module{
+			Path:     filepath.ToSlash(rel),
+			Versions: ReadList(path),
+			Dir:      path,
+		})
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules, nil
+}
+
+// ReadList returns the versions in the list file of an @v directory, sorted
+// by semantic version.
+func ReadList(atVDir string) []string {
+	data, err := os.ReadFile(filepath.Join(atVDir, "list"))
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			versions = append(versions, line)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return gomod.CompareVersions(versions[i], versions[j]) < 0 })
+	return versions
+}
+
+// VersionFile returns the path of a version's file with the given extension,
+// such as ".zip" or ".info", inside an @v directory.
+func VersionFile(atVDir, version, ext string) string {
+	return filepath.Join(atVDir, version+ext)
+}
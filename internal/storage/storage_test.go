@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListModules(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("github.com/gin-gonic/gin/@v/list", "v1.9.1\nv1.10.0\nv1.9.0\n")
+	write("github.com/gin-gonic/gin/v2/@v/list", "v2.0.0\n")
+	write("golang.org/x/net/@v/list", "v0.1.0\n")
+	write("_quarantine/example.com/x/@v/list", "v1.0.0\n")
+
+	modules, err := ListModules(root)
+	if err != nil {
+		t.Fatalf("ListModules failed: %v", err)
+	}
+
+	want := []string{"github.com/gin-gonic/gin", "github.com/gin-gonic/gin/v2", "golang.org/x/net"}
+	if len(modules) != len(want) {
+		t.Fatalf("Got %d modules, want %d: %+v", len(modules), len(want), modules)
+	}
+	for i, path := range want {
+		if modules[i].Path != path {
+			t.Errorf("Module %d: got %s, want %s", i, modules[i].Path, path)
+		}
+	}
+
+	versions := modules[0].Versions
+	if len(versions) != 3 || versions[0] != "v1.9.0" || versions[2] != "v1.10.0" {
+		t.Errorf("Versions not sorted by semver: %v", versions)
+	}
+}
@@ -0,0 +1,65 @@
+package upstream
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/retry"
+)
+
+// DefaultTimeout bounds a single HTTP request to an upstream.
+const DefaultTimeout = 2 * time.Minute
+
+// maxBodySize bounds how much of a response is read; module zips are limited
+// to 500 MB by the go command.
+const maxBodySize = 512 << 20
+
+// Client fetches files from upstream HTTP servers such as module proxies and
+// the vulnerability database, retrying transient failures.
+type Client struct {
+	HTTP  *http.Client
+	Retry retry.Policy
+}
+
+func NewClient(policy retry.Policy) *Client {
+	return &Client{
+		HTTP:  &http.Client{Timeout: DefaultTimeout},
+		Retry: policy,
+	}
+}
+
+// Get returns the body of url. Responses other than 200 OK are reported as
+// *retry.StatusError; 404, 410 and other client errors are not retried.
+func (c *Client) Get(url string) ([]byte, error) {
+	var body []byte
+	err := c.Retry.Do("GET "+url, func() error {
+		var err error
+		body, err = c.get(url)
+		return retry.ClassifyHTTP(err)
+	})
+	return body, err
+}
+
+func (c *Client) get(url string) ([]byte, error) {
+	resp, err := c.HTTP.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, &retry.StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	if len(body) > maxBodySize {
+		return nil, fmt.Errorf("GET %s: response larger than %d bytes", url, maxBodySize)
+	}
+	return body, nil
+}
@@ -0,0 +1,164 @@
+package vulndb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/log"
+	"github.com/example/go-mod-clone/internal/upstream"
+)
+
+// DefaultURL is the public Go vulnerability database.
+const DefaultURL = "https://vuln.go.dev"
+
+// Dir returns where the vulnerability database is kept in a storage root.
+// The directory name has no dot, so it cannot collide with a module path.
+func Dir(storageRoot string) string {
+	return filepath.Join(storageRoot, "vulndb")
+}
+
+// MirrorStats summarizes a mirror run.
+type MirrorStats struct {
+	Entries    int // entries in the upstream index
+	Downloaded int // entries fetched because they were new or modified
+}
+
+// Mirror copies the vulnerability database at baseURL into dir, keeping the
+// v1 layout (index/db.json, index/modules.json, index/vulns.json and
+// ID/<id>.json). Every file is stored both plain and gzipped, so the copy can
+// be used as a file:// database and served over HTTP to govulncheck. Entries
+// whose modified time did not change since the last mirror are not fetched.
+func Mirror(client *upstream.Client, baseURL, dir string) (*MirrorStats, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	get := func(endpoint string) ([]byte, error) {
+		return client.Get(baseURL + "/" + endpoint)
+	}
+
+	dbData, err := get("index/db.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch database index: %w", err)
+	}
+	var db DBIndex
+	if err := json.Unmarshal(dbData, &db); err != nil {
+		return nil, fmt.Errorf("invalid index/db.json: %w", err)
+	}
+
+	var local DBIndex
+	if data, err := os.ReadFile(filepath.Join(dir, "index", "db.json")); err == nil {
+		json.Unmarshal(data, &local)
+	}
+
+	vulnsData, err := get("index/vulns.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vulnerability index: %w", err)
+	}
+	var vulns []VulnIndex
+	if err := json.Unmarshal(vulnsData, &vulns); err != nil {
+		return nil, fmt.Errorf("invalid index/vulns.json: %w", err)
+	}
+	stats := &MirrorStats{Entries: len(vulns)}
+	if !local.Modified.IsZero() && local.Modified.Equal(db.Modified) {
+		log.Info("Vulnerability database is up to date (modified %s)", db.Modified.Format("2006-01-02 15:04"))
+		return stats, nil
+	}
+
+	modulesData, err := get("index/modules.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch module index: %w", err)
+	}
+	var modules []ModuleIndex
+	if err := json.Unmarshal(modulesData, &modules); err != nil {
+		return nil, fmt.Errorf("invalid index/modules.json: %w", err)
+	}
+
+	localModified := loadLocalVulnIndex(dir)
+	for _, v := range vulns {
+		if !validID(v.ID) {
+			return nil, fmt.Errorf("invalid vulnerability id %q", v.ID)
+		}
+		target := filepath.Join(dir, "ID", v.ID+".json")
+		if m, ok := localModified[v.ID]; ok && m.Equal(v.Modified) {
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+		}
+		data, err := get("ID/" + v.ID + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", v.ID, err)
+		}
+		if err := writeEndpoint(target, data); err != nil {
+			return nil, err
+		}
+		stats.Downloaded++
+	}
+
+	// The indexes are written last so that an interrupted run is retried
+	// in full instead of being mistaken for an up-to-date mirror
+	for _, idx := range []struct {
+		name string
+		data []byte
+	}{
+		{"modules.json", modulesData},
+		{"vulns.json", vulnsData},
+		{"db.json", dbData},
+	} {
+		if err := writeEndpoint(filepath.Join(dir, "index", idx.name), idx.data); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Mirrored vulnerability database: %d entries, %d downloaded", stats.Entries, stats.Downloaded)
+	return stats, nil
+}
+
+func loadLocalVulnIndex(dir string) map[string]time.Time {
+	modified := make(map[string]time.Time)
+	data, err := os.ReadFile(filepath.Join(dir, "index", "vulns.json"))
+	if err != nil {
+		return modified
+	}
+	var vulns []VulnIndex
+	if json.Unmarshal(data, &vulns) == nil {
+		for _, v := range vulns {
+			modified[v.ID] = v.Modified
+		}
+	}
+	return modified
+}
+
+// validID guards against ids that would escape the ID directory.
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && !strings.HasPrefix(id, ".")
+}
+
+// writeEndpoint writes data to file and a gzipped copy to file.gz.
+func writeEndpoint(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, data); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(file+".gz", buf.Bytes())
+}
+
+func writeFileAtomic(file string, data []byte) error {
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
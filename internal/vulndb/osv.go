@@ -0,0 +1,112 @@
+package vulndb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/gomod"
+)
+
+// DBIndex is index/db.json.
+type DBIndex struct {
+	Modified time.Time `json:"modified"`
+}
+
+// ModuleIndex is one element of index/modules.json.
+type ModuleIndex struct {
+	Path  string       `json:"path"`
+	Vulns []ModuleVuln `json:"vulns"`
+}
+
+// ModuleVuln is a vulnerability listed for a module in index/modules.json.
+type ModuleVuln struct {
+	ID       string    `json:"id"`
+	Modified time.Time `json:"modified"`
+	Fixed    string    `json:"fixed,omitempty"`
+}
+
+// VulnIndex is one element of index/vulns.json.
+type VulnIndex struct {
+	ID       string    `json:"id"`
+	Modified time.Time `json:"modified"`
+	Aliases  []string  `json:"aliases,omitempty"`
+}
+
+// Entry is the subset of an OSV entry (ID/<id>.json) needed for matching.
+type Entry struct {
+	ID       string     `json:"id"`
+	Modified time.Time  `json:"modified"`
+	Aliases  []string   `json:"aliases,omitempty"`
+	Summary  string     `json:"summary,omitempty"`
+	Details  string     `json:"details,omitempty"`
+	Affected []Affected `json:"affected"`
+}
+
+// Affected lists the affected versions of one package.
+type Affected struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Ranges []Range `json:"ranges,omitempty"`
+}
+
+// Range is an OSV version range. Go entries only use SEMVER ranges, whose
+// versions carry no "v" prefix.
+type Range struct {
+	Type   string       `json:"type"`
+	Events []RangeEvent `json:"events"`
+}
+
+// RangeEvent opens or closes an affected interval.
+type RangeEvent struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+// AffectsModule reports whether version of module modPath is affected by the
+// entry, and returns the version that fixes it, if any.
+func (e *Entry) AffectsModule(modPath, version string) (bool, string) {
+	for _, a := range e.Affected {
+		if a.Package.Ecosystem != "Go" || a.Package.Name != modPath {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			if ok, fixed := inRange(r.Events, version); ok {
+				return true, fixed
+			}
+		}
+	}
+	return false, ""
+}
+
+// inRange walks the events in order, tracking whether version lies in an
+// [introduced, fixed) interval.
+func inRange(events []RangeEvent, version string) (bool, string) {
+	affected := false
+	for _, ev := range events {
+		switch {
+		case ev.Introduced != "":
+			if ev.Introduced == "0" || gomod.CompareVersions(version, osvVersion(ev.Introduced)) >= 0 {
+				affected = true
+			}
+		case ev.Fixed != "":
+			if gomod.CompareVersions(version, osvVersion(ev.Fixed)) >= 0 {
+				affected = false
+			} else if affected {
+				return true, osvVersion(ev.Fixed)
+			}
+		}
+	}
+	return affected, ""
+}
+
+func osvVersion(v string) string {
+	if strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
@@ -0,0 +1,82 @@
+package vulndb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/example/go-mod-clone/internal/storage"
+)
+
+// Finding is a mirrored module version affected by a vulnerability.
+type Finding struct {
+	Path    string   `json:"path"`
+	Version string   `json:"version"`
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"`
+	Fixed   string   `json:"fixed,omitempty"` // first fixed version, empty if none
+	Summary string   `json:"summary,omitempty"`
+}
+
+// Scan matches every version of modules against the vulnerability database
+// in dir, as written by Mirror.
+func Scan(dir string, modules []storage.Module) ([]Finding, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "index", "modules.json"))
+	if err != nil {
+		return nil, fmt.Errorf("no vulnerability database in %s: %w", dir, err)
+	}
+	var index []ModuleIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid index/modules.json: %w", err)
+	}
+	byPath := make(map[string][]ModuleVuln, len(index))
+	for _, m := range index {
+		byPath[m.Path] = m.Vulns
+	}
+
+	entries := make(map[string]*Entry)
+	load := func(id string) (*Entry, error) {
+		if e, ok := entries[id]; ok {
+			return e, nil
+		}
+		if !validID(id) {
+			return nil, fmt.Errorf("invalid vulnerability id %q", id)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "ID", id+".json"))
+		if err != nil {
+			return nil, err
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("invalid entry %s: %w", id, err)
+		}
+		entries[id] = &e
+		return &e, nil
+	}
+
+	var findings []Finding
+	for _, mod := range modules {
+		for _, v := range byPath[mod.Path] {
+			entry, err := load(v.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, version := range mod.Versions {
+				affected, fixed := entry.AffectsModule(mod.Path, version)
+				if !affected {
+					continue
+				}
+				findings = append(findings, Finding{
+					Path:    mod.Path,
+					Version: version,
+					ID:      entry.ID,
+					Aliases: entry.Aliases,
+					Fixed:   fixed,
+					Summary: entry.Summary,
+				})
+			}
+		}
+	}
+	return findings, nil
+}
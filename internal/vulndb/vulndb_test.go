@@ -0,0 +1,121 @@
+package vulndb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/example/go-mod-clone/internal/retry"
+	"github.com/example/go-mod-clone/internal/storage"
+	"github.com/example/go-mod-clone/internal/upstream"
+)
+
+// writeFixture builds a small vulnerability database in the v1 layout.
+func writeFixture(t *testing.T, dir string, modified time.Time) {
+	t.Helper()
+	write := func(rel string, v interface{}) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry := Entry{
+		ID:       "GO-2099-0001",
+		Modified: modified,
+		Aliases:  []string{"CVE-2099-0001"},
+		Summary:  "Panic in example.com/a",
+	}
+	var aff Affected
+	aff.Package.Name = "example.com/a"
+	aff.Package.Ecosystem = "Go"
+	aff.Ranges = []Range{{Type: "SEMVER", Events: []RangeEvent{
+		{Introduced: "0"},
+		{Fixed: "1.2.0"},
+		{Introduced: "1.3.0"},
+		{Fixed: "1.3.2"},
+	}}}
+	entry.Affected = []Affected{aff}
+
+	write("index/db.json", DBIndex{Modified: modified})
+	write("index/vulns.json", []VulnIndex{{ID: entry.ID, Modified: modified, Aliases: entry.Aliases}})
+	write("index/modules.json", []ModuleIndex{{Path: "example.com/a", Vulns: []ModuleVuln{{ID: entry.ID, Modified: modified, Fixed: "1.3.2"}}}})
+	write("ID/"+entry.ID+".json", entry)
+}
+
+func TestMirrorAndScan(t *testing.T) {
+	upstreamDir := t.TempDir()
+	writeFixture(t, upstreamDir, time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var requests int32
+	fs := http.FileServer(http.Dir(upstreamDir))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fs.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client := upstream.NewClient(retry.Policy{Attempts: 1})
+	mirrorDir := filepath.Join(t.TempDir(), "vulndb")
+
+	stats, err := Mirror(client, srv.URL, mirrorDir)
+	if err != nil {
+		t.Fatalf("Mirror failed: %v", err)
+	}
+	if stats.Entries != 1 || stats.Downloaded != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	for _, f := range []string{"index/db.json", "index/db.json.gz", "index/modules.json.gz", "ID/GO-2099-0001.json.gz"} {
+		if _, err := os.Stat(filepath.Join(mirrorDir, filepath.FromSlash(f))); err != nil {
+			t.Errorf("Missing %s: %v", f, err)
+		}
+	}
+
+	// An unchanged database only costs the index requests
+	atomic.StoreInt32(&requests, 0)
+	if stats, err = Mirror(client, srv.URL, mirrorDir); err != nil {
+		t.Fatalf("Second mirror failed: %v", err)
+	}
+	if stats.Downloaded != 0 || atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("Second mirror downloaded %d entries with %d requests", stats.Downloaded, requests)
+	}
+
+	modules := []storage.Module{
+		{Path: "example.com/a", Versions: []string{"v1.1.0", "v1.2.0", "v1.3.1", "v1.3.2"}},
+		{Path: "example.com/b", Versions: []string{"v1.0.0"}},
+	}
+	findings, err := Scan(mirrorDir, modules)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	want := map[string]string{"v1.1.0": "v1.2.0", "v1.3.1": "v1.3.2"}
+	if len(findings) != len(want) {
+		t.Fatalf("Got findings %+v, want versions %v", findings, want)
+	}
+	for _, f := range findings {
+		if fixed, ok := want[f.Version]; !ok || f.Fixed != fixed || f.ID != "GO-2099-0001" {
+			t.Errorf("Unexpected finding %+v", f)
+		}
+	}
+}
+
+func TestMirror_UpstreamMissing(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	client := upstream.NewClient(retry.Policy{Attempts: 3})
+	if _, err := Mirror(client, srv.URL, t.TempDir()); err == nil {
+		t.Error("Expected error when the upstream has no database")
+	}
+}